@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/client/quoteclient"
+)
+
+// newFlagSet returns the flag set of a command; usage is its synopsis line.
+func newFlagSet(name, usage string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: client [flags] %s\n\n", usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(flags.Output(), "unexpected arguments %v\n", flags.Args())
+		return errUsage
+	}
+	return nil
+}
+
+func (c *client) runGet(ctx context.Context, args []string) error {
+	flags := newFlagSet("get", "get")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	sink, err := c.openSink()
+	if err != nil {
+		return err
+	}
+	defer sink.Close()
+	return c.get(ctx, sink)
+}
+
+func (c *client) openSink() (Sink, error) {
+	return newSink(c.cfg.sink, c.cfg.output, c.cfg.mode, c.cfg.format)
+}
+
+// get fetches the quote of cfg.pair and writes it to sink; unless the sink is stdout
+// the bid is printed as well. With an offline queue a failed fetch is recorded, and a
+// successful one first fills the gaps left by the queued ones.
+func (c *client) get(ctx context.Context, sink Sink) error {
+	quote, err := c.api.Quote(ctx, c.cfg.pair)
+	if err != nil {
+		if c.queue != nil && ctx.Err() == nil {
+			if qerr := c.queue.record(time.Now(), c.cfg.pair, err); qerr != nil {
+				log.Printf("recording failed fetch: %v", qerr)
+			}
+		}
+		return err
+	}
+
+	if c.queue != nil {
+		if err := c.reconcile(ctx, sink, c.cfg.pair); err != nil {
+			log.Print(err)
+		}
+	}
+
+	if c.cfg.sink != SinkStdout {
+		fmt.Println(quote.Bid)
+	}
+	return sink.Write(Record{
+		Time:           time.Now(),
+		Pair:           c.cfg.pair,
+		Bid:            quote.Bid,
+		QuoteTimestamp: quote.Timestamp,
+		Stale:          quote.Stale,
+	})
+}
+
+func (c *client) runHistory(ctx context.Context, args []string) error {
+	flags := newFlagSet("history", "history [-from date] [-to date] [-limit n]")
+	from := flags.String("from", "", "first quote time, unix seconds, RFC 3339 or YYYY-MM-DD")
+	to := flags.String("to", "", "last quote time")
+	limit := flags.Int("limit", 10, "number of quotes, newest first")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	filter := quoteclient.HistoryFilter{Pair: c.cfg.pair, Limit: *limit}
+	var err error
+	if filter.From, err = parseTime(*from); err != nil {
+		log.Printf("invalid -from: %v", err)
+		return errUsage
+	}
+	if filter.To, err = parseTime(*to); err != nil {
+		log.Printf("invalid -to: %v", err)
+		return errUsage
+	}
+
+	quotes, err := c.api.History(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tPAIR\tBID\tASK")
+	for _, quote := range quotes {
+		fmt.Fprintf(w, "%s\t%s-%s\t%s\t%s\n", time.Unix(quote.Timestamp, 0).UTC().Format(time.RFC3339), quote.Code, quote.Codein, quote.Bid, quote.Ask)
+	}
+	return w.Flush()
+}
+
+// runWatch runs get every interval until count runs are done, or until interrupted.
+// Failed runs are logged and the watch goes on; the last run decides the exit code.
+func (c *client) runWatch(ctx context.Context, args []string) error {
+	flags := newFlagSet("watch", "watch [-interval 10s] [-count n]")
+	interval := flags.Duration("interval", 10*time.Second, "time between two quotes")
+	count := flags.Int("count", 0, "number of quotes, 0 runs until interrupted")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		log.Print("-interval must be positive")
+		return errUsage
+	}
+
+	sink, err := c.openSink()
+	if err != nil {
+		return err
+	}
+	defer sink.Close()
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for n := 1; ; n++ {
+		err := c.get(ctx, sink)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if *count > 0 && n >= *count {
+			return err
+		}
+		if err != nil {
+			log.Print(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *client) runConvert(ctx context.Context, args []string) error {
+	code, codein, _ := strings.Cut(c.cfg.pair, "-")
+	flags := newFlagSet("convert", "convert -amount n [-from USD] [-to BRL] [-side bid|ask]")
+	from := flags.String("from", code, "currency to convert from, the first of -pair by default")
+	to := flags.String("to", codein, "currency to convert to, the second of -pair by default")
+	amount := flags.String("amount", "", "amount to convert")
+	side := flags.String("side", "bid", "price used, bid or ask")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *amount == "" {
+		fmt.Fprintln(flags.Output(), "-amount is required")
+		return errUsage
+	}
+
+	res, err := c.api.Convert(ctx, quoteclient.ConvertRequest{From: *from, To: *to, Amount: *amount, Side: *side})
+	if err != nil {
+		return err
+	}
+
+	via := ""
+	if len(res.Pairs) > 0 {
+		via = " via " + strings.Join(res.Pairs, ", ")
+	}
+	fmt.Printf("%s %s = %s %s (%s rate %s%s)\n", res.Amount, res.From, res.Result, res.To, res.Side, res.Rate, via)
+	return nil
+}
+
+// parseTime accepts unix seconds, RFC 3339 or a plain date (YYYY-MM-DD, UTC), as the
+// server does; empty is the zero time.
+func parseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if unix, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, v)
+}
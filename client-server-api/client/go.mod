@@ -1,3 +1,5 @@
 module github.com/philippe-berto/pos-goexpert-challenges/client-server-api/client
 
 go 1.22.3
+
+require github.com/mattn/go-sqlite3 v1.14.24
@@ -2,90 +2,227 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/client/quoteclient"
 )
 
 const (
-	TimeoutError = "API_CALL_TIMEOUT"
-	TimeoutMS    = 300 * time.Millisecond
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+	exitTimeout = 3
+	exitServer  = 4
+	exitBadData = 5
+
+	defaultURL     = "http://localhost:8080"
+	defaultTimeout = 300 * time.Millisecond
+	defaultOutput  = "cotacao"
+	defaultPair    = "USD-BRL"
+	defaultBackoff = 200 * time.Millisecond
 )
 
-type Response struct {
-	Err   *string `json:"err"`
-	Value *string `json:"value"`
-}
+const usage = `usage: client [flags] [command] [command flags]
+
+Commands:
+  get      fetch the quote of -pair and write it to -sink (default)
+  history  list the stored quotes of -pair
+  watch    fetch the quote of -pair every -interval
+  convert  convert an amount between currencies
+
+Flags default to QUOTE_SERVER_URL, QUOTE_TIMEOUT, QUOTE_OUTPUT, QUOTE_PAIR, QUOTE_SINK,
+QUOTE_MODE, QUOTE_FORMAT, QUOTE_RETRIES, QUOTE_BACKOFF, QUOTE_DEADLINE and QUOTE_QUEUE
+when set. The -format template gets .Time, .Pair, .Bid, .QuoteTimestamp, .Stale and
+.Label ("Dólar" for USD-BRL).
+Exit codes: 0 ok, 1 failure, 2 usage or rejected request, 3 timeout, 4 server error,
+5 bad data.
+Run "client <command> -h" for the flags of a command.
+
+`
+
+// errUsage is returned for bad flags and arguments.
+var errUsage = errors.New("usage")
+
+type (
+	// client runs the commands; queue is nil without an offline queue.
+	client struct {
+		cfg   config
+		api   *quoteclient.Client
+		queue *offlineQueue
+	}
+
+	// config holds the flags shared by every command; timeout bounds each request.
+	config struct {
+		url     string
+		timeout time.Duration
+		output  string
+		pair    string
+		sink    string
+		mode    string
+		format  string
+		// retries is the number of attempts after the first one, within deadline when set.
+		retries  int
+		backoff  time.Duration
+		deadline time.Duration
+		queue    string
+	}
+)
 
 func main() {
-	ctx := context.Background()
+	os.Exit(run(os.Args[1:]))
+}
 
-	res, err := callServer(ctx)
+// run parses args and runs the command; it returns the process exit code.
+func run(args []string) int {
+	cfg, args, err := parseConfig(args)
 	if err != nil {
-		log.Println(err)
-		return
+		return exitUsage
 	}
-	defer res.Body.Close()
 
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		log.Println(err)
-		return
+	command := "get"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
 	}
 
-	resJson := Response{}
-	err = json.Unmarshal(body, &resJson)
-	if err != nil {
-		log.Println(err)
-		return
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	c := &client{cfg: cfg, api: quoteclient.New(quoteclient.Config{
+		BaseURL:  cfg.url,
+		Timeout:  cfg.timeout,
+		Retries:  cfg.retries,
+		Backoff:  cfg.backoff,
+		Deadline: cfg.deadline,
+	})}
+	if cfg.queue != "" {
+		c.queue = &offlineQueue{path: cfg.queue}
 	}
-
-	if resJson.Err == nil {
-		writeFile(*resJson.Value)
+	switch command {
+	case "get":
+		err = c.runGet(ctx, args)
+	case "history":
+		err = c.runHistory(ctx, args)
+	case "watch":
+		err = c.runWatch(ctx, args)
+	case "convert":
+		err = c.runConvert(ctx, args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		return exitUsage
 	}
 
-	log.Println("res", string(body))
-	log.Println("res.Status", res.Status)
+	// a bare errUsage was reported by the flag set already
+	if err != nil && err != errUsage {
+		log.Print(err)
+	}
+	return exitCode(err)
 }
 
-func callServer(c context.Context) (*http.Response, error) {
-	ctx, cancel := context.WithTimeout(c, TimeoutMS)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost:8080/cotacao", nil)
+func parseConfig(args []string) (config, []string, error) {
+	cfg := config{}
+	timeout, err := envDuration("QUOTE_TIMEOUT", defaultTimeout)
+	if err != nil {
+		log.Print(err)
+		return cfg, nil, err
+	}
+	backoff, err := envDuration("QUOTE_BACKOFF", defaultBackoff)
+	if err != nil {
+		log.Print(err)
+		return cfg, nil, err
+	}
+	deadline, err := envDuration("QUOTE_DEADLINE", 0)
 	if err != nil {
-		return nil, err
+		log.Print(err)
+		return cfg, nil, err
+	}
+	retries, err := strconv.Atoi(envOr("QUOTE_RETRIES", "0"))
+	if err != nil {
+		log.Printf("invalid QUOTE_RETRIES: %v", err)
+		return cfg, nil, err
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	switch {
-	case ctx.Err() != nil:
-		return nil, errors.New(TimeoutError)
-	case err != nil:
-		return nil, err
+	flags := flag.NewFlagSet("client", flag.ContinueOnError)
+	flags.StringVar(&cfg.url, "url", envOr("QUOTE_SERVER_URL", defaultURL), "server base URL")
+	flags.DurationVar(&cfg.timeout, "timeout", timeout, "timeout of each request")
+	flags.StringVar(&cfg.output, "o", envOr("QUOTE_OUTPUT", ""), "output file of the sink, cotacao.<txt|jsonl|csv|db> when empty")
+	flags.StringVar(&cfg.pair, "pair", envOr("QUOTE_PAIR", defaultPair), "currency pair")
+	flags.StringVar(&cfg.sink, "sink", envOr("QUOTE_SINK", SinkText), "where quotes go: text, jsonl, csv, stdout or sqlite")
+	flags.StringVar(&cfg.mode, "mode", envOr("QUOTE_MODE", ModeAppend), "append, or overwrite to keep only the latest quote")
+	flags.StringVar(&cfg.format, "format", envOr("QUOTE_FORMAT", defaultFormat), "line template of the text and stdout sinks")
+	flags.IntVar(&cfg.retries, "retries", retries, "attempts after a timeout, network failure, 5xx or 429")
+	flags.DurationVar(&cfg.backoff, "backoff", backoff, "wait before the first retry, doubled on every retry")
+	flags.DurationVar(&cfg.deadline, "deadline", deadline, "time limit of all the attempts of a request, none when 0")
+	flags.StringVar(&cfg.queue, "queue", envOr("QUOTE_QUEUE", ""), "offline queue file of failed fetches, none when empty")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return cfg, nil, err
 	}
 
-	return resp, nil
+	cfg.url = strings.TrimRight(cfg.url, "/")
+	cfg.pair = strings.ToUpper(strings.TrimSpace(cfg.pair))
+	if cfg.output == "" {
+		cfg.output = defaultOutput + "." + sinkExtensions[cfg.sink]
+	}
+	if cfg.timeout <= 0 {
+		log.Print("-timeout must be positive")
+		return cfg, nil, errUsage
+	}
+	if cfg.retries < 0 || cfg.backoff < 0 || cfg.deadline < 0 {
+		log.Print("-retries, -backoff and -deadline must not be negative")
+		return cfg, nil, errUsage
+	}
+	return cfg, flags.Args(), nil
 }
 
-func writeFile(valor string) {
-	data := fmt.Sprintf("Dólar: %s\n", valor)
-
-	file, err := os.OpenFile("cotacao.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Fatal(err)
+// exitCode maps the error of a command to the process exit code.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage), errors.Is(err, quoteclient.ErrClient):
+		return exitUsage
+	case errors.Is(err, quoteclient.ErrTimeout):
+		return exitTimeout
+	case errors.Is(err, quoteclient.ErrServer):
+		return exitServer
+	case errors.Is(err, quoteclient.ErrBadData):
+		return exitBadData
+	default:
+		return exitFailure
 	}
-	defer file.Close()
+}
 
-	if _, err := file.WriteString(data); err != nil {
-		log.Fatal(err)
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
 	}
+	return fallback
+}
 
-	log.Println("File written successfully")
+// envDuration reads key as a Go duration ("500ms") or plain milliseconds.
+func envDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return d, nil
+	}
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: use a duration such as 500ms", key, v)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cotacao/USD-BRL":
+			w.Write([]byte(`{"Err":null,"Code":null,"Value":"5.35"}`))
+		case "/cotacao/EUR-BRL":
+			w.WriteHeader(http.StatusGatewayTimeout)
+			w.Write([]byte(`{"Err":"EXTERNAL_API_CALL_TIMEOUT","Code":"EXTERNAL_API_CALL_TIMEOUT"}`))
+		case "/cotacao/GBP-BRL":
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`{"Err":"EXTERNAL_API_CALL_FAILED","Code":"EXTERNAL_API_CALL_FAILED"}`))
+		case "/cotacao/XYZ-BRL":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"Err":"UNSUPPORTED_CURRENCY_PAIR: XYZ-BRL","Code":"UNSUPPORTED_CURRENCY_PAIR"}`))
+		case "/cotacao/JPY-BRL":
+			w.Write([]byte(`{"Value":"five"}`))
+		case "/cotacao/BTC-BRL":
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	output := filepath.Join(t.TempDir(), "cotacao.txt")
+	cases := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"should append the quote", []string{"-pair", "usd-brl"}, exitOK},
+		{"should exit on a server timeout", []string{"-pair", "EUR-BRL", "get"}, exitTimeout},
+		{"should exit on a server error", []string{"-pair", "GBP-BRL"}, exitServer},
+		{"should exit on a rejected request", []string{"-pair", "XYZ-BRL"}, exitUsage},
+		{"should exit on bad data", []string{"-pair", "JPY-BRL"}, exitBadData},
+		{"should exit on a client timeout", []string{"-pair", "BTC-BRL", "-timeout", "20ms"}, exitTimeout},
+		{"should reject an unknown command", []string{"quote"}, exitUsage},
+		{"should reject a convert without amount", []string{"convert"}, exitUsage},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := append([]string{"-url", server.URL, "-o", output}, tc.args...)
+			if got := run(args); got != tc.want {
+				t.Errorf("run(%v) = %d, want %d", tc.args, got, tc.want)
+			}
+		})
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "Dólar: 5.35\n" {
+		t.Errorf("output = %q", data)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/client/quoteclient"
+)
+
+type (
+	// failedFetch is a fetch that failed, as recorded in the offline queue.
+	failedFetch struct {
+		Time  time.Time `json:"time"`
+		Pair  string    `json:"pair"`
+		Error string    `json:"error"`
+	}
+
+	// offlineQueue is a JSON lines file of failed fetches, kept until a later run
+	// fills their gap in the sink.
+	offlineQueue struct {
+		path string
+	}
+)
+
+// record appends a failed fetch of pair at t to the queue.
+func (q *offlineQueue) record(t time.Time, pair string, cause error) error {
+	line, err := json.Marshal(failedFetch{Time: t, Pair: pair, Error: cause.Error()})
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(q.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (q *offlineQueue) load() ([]failedFetch, error) {
+	file, err := os.Open(q.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var fetches []failedFetch
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var f failedFetch
+		if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+			return nil, fmt.Errorf("reading offline queue %s: %w", q.path, err)
+		}
+		fetches = append(fetches, f)
+	}
+	return fetches, scanner.Err()
+}
+
+// save replaces the queue with fetches, removing the file when there are none.
+func (q *offlineQueue) save(fetches []failedFetch) error {
+	if len(fetches) == 0 {
+		err := os.Remove(q.path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return writeAtomic(q.path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		for _, f := range fetches {
+			if err := enc.Encode(f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// reconcile fills the gap of every queued fetch of pair with the newest quote the
+// server stored before it, written to sink at the time of the failed fetch and flagged
+// Stale. A fetch the server has no quote for is logged as a gap and dropped; one that
+// could not be looked up stays queued for the next run.
+func (c *client) reconcile(ctx context.Context, sink Sink, pair string) error {
+	fetches, err := c.queue.load()
+	if err != nil || len(fetches) == 0 {
+		return err
+	}
+
+	var keep []failedFetch
+	for i, f := range fetches {
+		if f.Pair != pair {
+			keep = append(keep, f)
+			continue
+		}
+
+		quotes, err := c.api.History(ctx, quoteclient.HistoryFilter{Pair: pair, To: f.Time, Limit: 1})
+		if err != nil {
+			keep = append(keep, fetches[i:]...)
+			return errors.Join(fmt.Errorf("reconciling %s at %s: %w", pair, f.Time.Format(time.RFC3339), err), c.queue.save(keep))
+		}
+		if len(quotes) == 0 {
+			log.Printf("gap in %s at %s: no quote stored before it (%s)", pair, f.Time.Format(time.RFC3339), f.Error)
+			continue
+		}
+
+		quote := quotes[0]
+		err = sink.Write(Record{Time: f.Time, Pair: pair, Bid: quote.Bid, QuoteTimestamp: quote.Timestamp, Stale: true})
+		if err != nil {
+			keep = append(keep, fetches[i:]...)
+			return errors.Join(err, c.queue.save(keep))
+		}
+	}
+
+	return c.queue.save(keep)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestOfflineQueue(t *testing.T) {
+	online := &atomic.Bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case !online.Load():
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/cotacao/history":
+			w.Write([]byte(`{"Quotes":[{"code":"USD","codein":"BRL","bid":"5.30","ask":"5.31","timestamp":"1717171200"}]}`))
+		default:
+			w.Write([]byte(`{"Value":"5.35","Timestamp":1717171300}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	output, queue := filepath.Join(dir, "quotes.csv"), filepath.Join(dir, "queue.jsonl")
+	args := []string{"-url", server.URL, "-sink", "csv", "-o", output, "-queue", queue}
+
+	for range 2 {
+		if code := run(args); code != exitServer {
+			t.Fatalf("offline run exited %d, want %d", code, exitServer)
+		}
+	}
+	if data, _ := os.ReadFile(queue); strings.Count(string(data), "\n") != 2 {
+		t.Fatalf("queue = %q, want 2 failed fetches", data)
+	}
+
+	online.Store(true)
+	if code := run(args); code != exitOK {
+		t.Fatalf("online run exited %d", code)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(rows) != 4 || !strings.HasSuffix(rows[1], ",USD-BRL,5.30,1717171200,true") || !strings.HasSuffix(rows[3], ",USD-BRL,5.35,1717171300,false") {
+		t.Errorf("rows = %q, want header, 2 backfilled gaps and the fresh quote", rows)
+	}
+	if _, err := os.Stat(queue); !os.IsNotExist(err) {
+		t.Errorf("queue not cleared: %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package quoteclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Alert rules are only served when the server runs with ALERTS_ENABLED; otherwise these
+// methods fail with a 404 *Error.
+
+// Rules lists the alert rules, only those of pair when not empty.
+func (c *Client) Rules(ctx context.Context, pair string) ([]Rule, error) {
+	query := url.Values{}
+	if pair != "" {
+		query.Set("pair", pair)
+	}
+
+	var res struct {
+		Rules []Rule `json:"rules"`
+	}
+	err := c.do(ctx, http.MethodGet, "/alerts", query, nil, &res)
+	return res.Rules, err
+}
+
+func (c *Client) Rule(ctx context.Context, id int64) (*Rule, error) {
+	return c.rule(ctx, http.MethodGet, rulePath(id), nil)
+}
+
+func (c *Client) CreateRule(ctx context.Context, req RuleRequest) (*Rule, error) {
+	return c.rule(ctx, http.MethodPost, "/alerts", req)
+}
+
+// UpdateRule replaces the rule id with req.
+func (c *Client) UpdateRule(ctx context.Context, id int64, req RuleRequest) (*Rule, error) {
+	return c.rule(ctx, http.MethodPut, rulePath(id), req)
+}
+
+func (c *Client) DeleteRule(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, rulePath(id), nil, nil, nil)
+}
+
+// Deliveries lists the latest webhook deliveries of the rule id, newest first; limit is
+// left to the server when zero.
+func (c *Client) Deliveries(ctx context.Context, id int64, limit int) ([]Delivery, error) {
+	query := url.Values{}
+	setInt(query, "limit", limit)
+
+	var res struct {
+		Deliveries []Delivery `json:"deliveries"`
+	}
+	err := c.do(ctx, http.MethodGet, rulePath(id)+"/deliveries", query, nil, &res)
+	return res.Deliveries, err
+}
+
+func (c *Client) rule(ctx context.Context, method, path string, body any) (*Rule, error) {
+	var res struct {
+		Rule *Rule `json:"rule"`
+	}
+	if err := c.do(ctx, method, path, nil, body, &res); err != nil {
+		return nil, err
+	}
+	return res.Rule, nil
+}
+
+func rulePath(id int64) string {
+	return "/alerts/" + strconv.FormatInt(id, 10)
+}
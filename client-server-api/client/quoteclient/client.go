@@ -0,0 +1,306 @@
+// Package quoteclient is the Go client of the quote server.
+package quoteclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TimeoutError = "API_CALL_TIMEOUT"
+	ServerError  = "SERVER_ERROR"
+	ClientError  = "CLIENT_ERROR"
+	BadDataError = "BAD_RESPONSE_DATA"
+
+	// MaxBackoff caps the wait between two attempts, Retry-After aside.
+	MaxBackoff = 10 * time.Second
+)
+
+var (
+	// ErrTimeout matches a request that did not finish within Config.Timeout or
+	// Config.Deadline, and an *Error of the server running out of time.
+	ErrTimeout = errors.New(TimeoutError)
+	// ErrClient matches an *Error with a 4xx status other than 429: the request was
+	// rejected and sending it again would not help.
+	ErrClient = errors.New(ClientError)
+	// ErrServer matches every other *Error.
+	ErrServer = errors.New(ServerError)
+	// ErrBadData is returned when an answer could not be decoded or misses its value.
+	ErrBadData = errors.New(BadDataError)
+)
+
+type (
+	Config struct {
+		// BaseURL is the server address, e.g. http://localhost:8080.
+		BaseURL string
+		// HTTPClient defaults to http.DefaultClient.
+		HTTPClient *http.Client
+		// Timeout bounds every attempt of a request, none when zero. Export and Stream
+		// only use it until the answer starts.
+		Timeout time.Duration
+		// Retries is the number of attempts after the first one, see Client.
+		Retries int
+		// Backoff is the wait before the first retry, doubled on every retry.
+		Backoff time.Duration
+		// Deadline bounds all the attempts of a request together, none when zero.
+		Deadline time.Duration
+	}
+
+	// Client calls the quote server. Reads are retried, up to Config.Retries times, after
+	// a timeout, a network failure, a 5xx or a 429, waiting Retry-After when the server
+	// sends it; a retry that would end past Config.Deadline is not made. Requests that
+	// change state (POST, PUT and DELETE) are never retried.
+	Client struct {
+		cfg     Config
+		baseURL string
+		http    *http.Client
+	}
+
+	// Error is an error answered by the server, with its machine readable Code.
+	Error struct {
+		Status     int
+		Code       string
+		Message    string
+		RetryAfter time.Duration
+	}
+
+	// envelope is the part every JSON answer of the server shares.
+	envelope struct {
+		Err  *string `json:"err"`
+		Code *string `json:"code"`
+	}
+)
+
+func New(cfg Config) *Client {
+	client := cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Client{cfg: cfg, baseURL: strings.TrimRight(cfg.BaseURL, "/"), http: client}
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s (%d)", e.Code, e.Status)
+	}
+	return fmt.Sprintf("%s (%d)", e.Message, e.Status)
+}
+
+// Is matches ErrTimeout for the *_TIMEOUT codes, ErrClient for the other 4xx but 429
+// and ErrServer for the rest.
+func (e *Error) Is(target error) bool {
+	switch {
+	case strings.HasSuffix(e.Code, "_TIMEOUT"):
+		return target == ErrTimeout
+	case e.Status >= 400 && e.Status < 500 && e.Status != http.StatusTooManyRequests:
+		return target == ErrClient
+	default:
+		return target == ErrServer
+	}
+}
+
+// retryable tells whether another attempt may succeed.
+func retryable(err error) bool {
+	var serr *Error
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, ErrBadData):
+		return false
+	case errors.As(err, &serr):
+		return serr.Status >= 500 || serr.Status == http.StatusTooManyRequests
+	default:
+		return true
+	}
+}
+
+// do sends the request, with retries when method is GET, and decodes
+// the JSON answer into out unless it is nil. body, when not nil, is sent as JSON.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	callCtx := ctx
+	if c.cfg.Deadline > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, c.cfg.Deadline)
+		defer cancel()
+	}
+
+	retries := c.cfg.Retries
+	if method != http.MethodGet {
+		retries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := c.attempt(ctx, callCtx, method, path, query, payload, out)
+		if err == nil || attempt >= retries || !retryable(err) {
+			return err
+		}
+
+		wait := c.backoff(attempt)
+		var serr *Error
+		if errors.As(err, &serr) && serr.RetryAfter > 0 {
+			wait = serr.RetryAfter
+		}
+		if deadline, ok := callCtx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-callCtx.Done():
+			timer.Stop()
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt makes one request within Config.Timeout and callCtx; ctx is the caller's.
+func (c *Client) attempt(ctx, callCtx context.Context, method, path string, query url.Values, payload []byte, out any) error {
+	res, cancel, err := c.send(ctx, callCtx, c.cfg.Timeout, method, path, query, payload)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	defer res.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return requestError(ctx, err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%w: %w", ErrBadData, err)
+	}
+	return nil
+}
+
+// send makes one request, bounded by timeout when positive, and returns a 2xx answer;
+// any other is read and returned as an *Error. cancel must be called once the body is
+// read.
+func (c *Client) send(ctx, callCtx context.Context, timeout time.Duration, method, path string, query url.Values, payload []byte) (*http.Response, context.CancelFunc, error) {
+	reqCtx, cancel := callCtx, context.CancelFunc(func() {})
+	if timeout > 0 {
+		reqCtx, cancel = context.WithTimeout(callCtx, timeout)
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(reqCtx, method, target, body)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		cancel()
+		return nil, nil, requestError(ctx, err)
+	}
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return res, cancel, nil
+	}
+
+	defer cancel()
+	defer res.Body.Close()
+	serr := &Error{Status: res.StatusCode, Code: ServerError, Message: res.Status, RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"))}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, requestError(ctx, err)
+	}
+	var env envelope
+	if json.Unmarshal(data, &env) == nil {
+		if env.Code != nil {
+			serr.Code = *env.Code
+		}
+		if env.Err != nil {
+			serr.Message = *env.Err
+		}
+	}
+	return nil, nil, serr
+}
+
+// requestError tells a request that ran out of time apart from one the caller
+// cancelled.
+func requestError(ctx context.Context, err error) error {
+	var nerr net.Error
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout
+	case errors.As(err, &nerr) && nerr.Timeout():
+		return ErrTimeout
+	default:
+		return err
+	}
+}
+
+// backoff doubles Config.Backoff on every attempt, up to MaxBackoff, and picks a random
+// wait in the upper half of it so clients failing together do not retry together.
+func (c *Client) backoff(attempt int) time.Duration {
+	if c.cfg.Backoff <= 0 {
+		return 0
+	}
+	d := c.cfg.Backoff << min(attempt, 30)
+	if d <= 0 || d > MaxBackoff {
+		d = MaxBackoff
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// parseRetryAfter reads delay-seconds or an HTTP date; 0 when absent or invalid.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+func setTime(query url.Values, key string, t time.Time) {
+	if !t.IsZero() {
+		query.Set(key, strconv.FormatInt(t.Unix(), 10))
+	}
+}
+
+func setInt(query url.Values, key string, v int) {
+	if v > 0 {
+		query.Set(key, strconv.Itoa(v))
+	}
+}
@@ -0,0 +1,265 @@
+package quoteclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, cfg Config) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	cfg.BaseURL = server.URL + "/"
+	cfg.HTTPClient = server.Client()
+	return New(cfg)
+}
+
+func TestQuote(t *testing.T) {
+	t.Run("should return the bid of the pair", func(t *testing.T) {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/cotacao/EUR-BRL" {
+				t.Errorf("path = %s", r.URL.Path)
+			}
+			w.Write([]byte(`{"Err":null,"Code":null,"Value":"6.1","Cached":true,"AgeMs":1500,"Timestamp":1717171200}`))
+		}, Config{})
+
+		quote, err := c.Quote(context.Background(), "EUR-BRL")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := QuoteResult{Pair: "EUR-BRL", Bid: "6.1", Cached: true, Age: 1500 * time.Millisecond, Timestamp: 1717171200}
+		if *quote != want {
+			t.Errorf("got %+v, want %+v", *quote, want)
+		}
+	})
+
+	t.Run("should type rejected requests as client errors", func(t *testing.T) {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"Err":"UNSUPPORTED_CURRENCY_PAIR: XYZ-BRL","Code":"UNSUPPORTED_CURRENCY_PAIR"}`))
+		}, Config{})
+
+		_, err := c.Quote(context.Background(), "XYZ-BRL")
+		var serr *Error
+		if !errors.As(err, &serr) || serr.Status != http.StatusBadRequest || serr.Code != "UNSUPPORTED_CURRENCY_PAIR" {
+			t.Fatalf("err = %#v", err)
+		}
+		if !errors.Is(err, ErrClient) || errors.Is(err, ErrServer) || errors.Is(err, ErrTimeout) {
+			t.Errorf("err %v should only match ErrClient", err)
+		}
+	})
+
+	t.Run("should tell timeouts apart", func(t *testing.T) {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/cotacao/BTC-BRL" {
+				time.Sleep(100 * time.Millisecond)
+				return
+			}
+			w.WriteHeader(http.StatusGatewayTimeout)
+			w.Write([]byte(`{"Err":"EXTERNAL_API_CALL_TIMEOUT","Code":"EXTERNAL_API_CALL_TIMEOUT"}`))
+		}, Config{Timeout: 20 * time.Millisecond})
+
+		if _, err := c.Quote(context.Background(), "USD-BRL"); !errors.Is(err, ErrTimeout) {
+			t.Errorf("server timeout: err = %v", err)
+		}
+		if _, err := c.Quote(context.Background(), "BTC-BRL"); !errors.Is(err, ErrTimeout) {
+			t.Errorf("client timeout: err = %v", err)
+		}
+	})
+
+	t.Run("should reject bad data", func(t *testing.T) {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"Value":"five"}`))
+		}, Config{})
+
+		if _, err := c.Quote(context.Background(), ""); !errors.Is(err, ErrBadData) {
+			t.Errorf("err = %v", err)
+		}
+	})
+}
+
+func TestRetries(t *testing.T) {
+	// flaky answers status with retryAfter for the first failures calls, then a quote.
+	flaky := func(t *testing.T, failures int32, status int, retryAfter string, cfg Config) (*Client, *atomic.Int32) {
+		calls := &atomic.Int32{}
+		cfg.Backoff = time.Millisecond
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) <= failures {
+				if retryAfter != "" {
+					w.Header().Set("Retry-After", retryAfter)
+				}
+				w.WriteHeader(status)
+				return
+			}
+			w.Write([]byte(`{"Value":"5.35","Rule":{"id":1}}`))
+		}, cfg)
+		return c, calls
+	}
+
+	t.Run("should retry server failures", func(t *testing.T) {
+		c, calls := flaky(t, 2, http.StatusServiceUnavailable, "", Config{Retries: 3})
+
+		if _, err := c.Quote(context.Background(), ""); err != nil || calls.Load() != 3 {
+			t.Errorf("err = %v after %d calls, want nil after 3", err, calls.Load())
+		}
+	})
+
+	t.Run("should give up after the retries", func(t *testing.T) {
+		c, calls := flaky(t, 10, http.StatusTooManyRequests, "", Config{Retries: 3})
+
+		if _, err := c.Quote(context.Background(), ""); !errors.Is(err, ErrServer) || calls.Load() != 4 {
+			t.Errorf("err = %v after %d calls, want ErrServer after 4", err, calls.Load())
+		}
+	})
+
+	t.Run("should not retry client errors nor changes", func(t *testing.T) {
+		c, calls := flaky(t, 10, http.StatusBadRequest, "", Config{Retries: 3})
+		if _, err := c.Quote(context.Background(), ""); !errors.Is(err, ErrClient) || calls.Load() != 1 {
+			t.Errorf("err = %v after %d calls, want ErrClient after 1", err, calls.Load())
+		}
+
+		c, calls = flaky(t, 10, http.StatusServiceUnavailable, "", Config{Retries: 3})
+		if _, err := c.CreateRule(context.Background(), RuleRequest{}); !errors.Is(err, ErrServer) || calls.Load() != 1 {
+			t.Errorf("err = %v after %d calls, want ErrServer after 1", err, calls.Load())
+		}
+		if _, err := c.UpdateRule(context.Background(), 1, RuleRequest{}); !errors.Is(err, ErrServer) || calls.Load() != 2 {
+			t.Errorf("err = %v after %d calls, want ErrServer after 2", err, calls.Load())
+		}
+		if err := c.DeleteRule(context.Background(), 1); !errors.Is(err, ErrServer) || calls.Load() != 3 {
+			t.Errorf("err = %v after %d calls, want ErrServer after 3", err, calls.Load())
+		}
+	})
+
+	t.Run("should stop when Retry-After is past the deadline", func(t *testing.T) {
+		c, calls := flaky(t, 10, http.StatusServiceUnavailable, "30", Config{Retries: 3, Deadline: time.Second})
+
+		start := time.Now()
+		_, err := c.Quote(context.Background(), "")
+		var serr *Error
+		if !errors.As(err, &serr) || serr.RetryAfter != 30*time.Second || calls.Load() != 1 || time.Since(start) > 500*time.Millisecond {
+			t.Errorf("err = %v after %d calls and %s", err, calls.Load(), time.Since(start))
+		}
+	})
+
+	t.Run("should wait for Retry-After", func(t *testing.T) {
+		c, calls := flaky(t, 1, http.StatusServiceUnavailable, "1", Config{Retries: 1})
+
+		start := time.Now()
+		if _, err := c.Quote(context.Background(), ""); err != nil || calls.Load() != 2 || time.Since(start) < time.Second {
+			t.Errorf("err = %v after %d calls and %s", err, calls.Load(), time.Since(start))
+		}
+	})
+
+	t.Run("should back off exponentially with jitter", func(t *testing.T) {
+		c := New(Config{Backoff: 100 * time.Millisecond})
+		for attempt, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond} {
+			if got := c.backoff(attempt); got < want/2 || got > want {
+				t.Errorf("backoff(%d) = %s, want between %s and %s", attempt, got, want/2, want)
+			}
+		}
+		if got := c.backoff(40); got > MaxBackoff {
+			t.Errorf("backoff(40) = %s, want at most %s", got, MaxBackoff)
+		}
+	})
+}
+
+func TestEndpoints(t *testing.T) {
+	t.Run("should send history filters", func(t *testing.T) {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if got := r.URL.RawQuery; got != "limit=1&pair=USD-BRL&to=1717171200" {
+				t.Errorf("query = %s", got)
+			}
+			w.Write([]byte(`{"Quotes":[{"code":"USD","codein":"BRL","bid":"5.35","ask":"5.36","timestamp":"1717171200"}],"Limit":1}`))
+		}, Config{})
+
+		quotes, err := c.History(context.Background(), HistoryFilter{Pair: "USD-BRL", To: time.Unix(1717171200, 0), Limit: 1})
+		if err != nil || len(quotes) != 1 || quotes[0].Bid != "5.35" || quotes[0].Timestamp != 1717171200 {
+			t.Errorf("quotes = %+v, err = %v", quotes, err)
+		}
+	})
+
+	t.Run("should convert", func(t *testing.T) {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/convert" || r.URL.Query().Get("amount") != "123.45" {
+				t.Errorf("url = %s", r.URL)
+			}
+			w.Write([]byte(`{"From":"USD","To":"BRL","Side":"bid","Amount":"123.45","Rate":"5","Result":"617.25","Pairs":["USD-BRL"]}`))
+		}, Config{})
+
+		conversion, err := c.Convert(context.Background(), ConvertRequest{From: "USD", To: "BRL", Amount: "123.45"})
+		if err != nil || conversion.Result != "617.25" || conversion.Pairs[0] != "USD-BRL" {
+			t.Errorf("conversion = %+v, err = %v", conversion, err)
+		}
+	})
+
+	t.Run("should create and delete rules", func(t *testing.T) {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method + " " + r.URL.Path {
+			case "POST /alerts":
+				var req RuleRequest
+				json.NewDecoder(r.Body).Decode(&req)
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprintf(w, `{"Rule":{"id":7,"pair":%q,"kind":%q,"threshold":%q,"enabled":true}}`, req.Pair, req.Kind, req.Threshold)
+			case "DELETE /alerts/7":
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"Err":"ALERT_RULE_NOT_FOUND","Code":"ALERT_RULE_NOT_FOUND"}`))
+			}
+		}, Config{})
+
+		rule, err := c.CreateRule(context.Background(), RuleRequest{Pair: "USD-BRL", Kind: "above", Threshold: "5.5"})
+		if err != nil || rule.ID != 7 || rule.Threshold != "5.5" {
+			t.Fatalf("rule = %+v, err = %v", rule, err)
+		}
+		if err := c.DeleteRule(context.Background(), 7); err != nil {
+			t.Fatal(err)
+		}
+		var serr *Error
+		if err := c.DeleteRule(context.Background(), 8); !errors.As(err, &serr) || serr.Code != "ALERT_RULE_NOT_FOUND" {
+			t.Errorf("err = %v", err)
+		}
+	})
+
+	t.Run("should stream quotes until dropped", func(t *testing.T) {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, ": heartbeat\n\n")
+			io.WriteString(w, "id: 1\nevent: quote\ndata: {\"code\":\"USD\",\"codein\":\"BRL\",\"bid\":\"5.35\",\"timestamp\":\"1\"}\n\n")
+			io.WriteString(w, "id: 2\nevent: quote\ndata: {\"code\":\"USD\",\"codein\":\"BRL\",\"bid\":\"5.36\",\"timestamp\":\"2\"}\n\n")
+			io.WriteString(w, "event: dropped\ndata: {}\n\n")
+		}, Config{Timeout: time.Second})
+
+		var bids []string
+		err := c.Stream(context.Background(), []string{"USD-BRL"}, func(q Quote) error {
+			bids = append(bids, q.Bid)
+			return nil
+		})
+		if !errors.Is(err, ErrStreamDropped) || len(bids) != 2 || bids[1] != "5.36" {
+			t.Errorf("bids = %v, err = %v", bids, err)
+		}
+	})
+
+	t.Run("should export into the writer", func(t *testing.T) {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("format") != "ndjson" {
+				t.Errorf("query = %s", r.URL.RawQuery)
+			}
+			io.WriteString(w, "{\"pair\":\"USD-BRL\"}\n")
+		}, Config{Timeout: time.Second})
+
+		var out bytes.Buffer
+		n, err := c.Export(context.Background(), ExportFilter{Format: "ndjson"}, &out)
+		if err != nil || n != int64(out.Len()) || out.String() != "{\"pair\":\"USD-BRL\"}\n" {
+			t.Errorf("export = %q (%d), err = %v", out.String(), n, err)
+		}
+	})
+}
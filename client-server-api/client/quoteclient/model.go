@@ -0,0 +1,144 @@
+package quoteclient
+
+import "time"
+
+// Prices and amounts are kept as the decimal strings the server sends, so nothing is
+// lost to float rounding; parse them with the decimal package of your choice.
+type (
+	// Quote mirrors the upstream payload stored by the server.
+	Quote struct {
+		Code       string `json:"code"`
+		Codein     string `json:"codein"`
+		Name       string `json:"name"`
+		High       string `json:"high"`
+		Low        string `json:"low"`
+		VarBid     string `json:"varBid"`
+		PctChange  string `json:"pctChange"`
+		Bid        string `json:"bid"`
+		Ask        string `json:"ask"`
+		Timestamp  int64  `json:"timestamp,string"`
+		CreateDate string `json:"create_date"`
+	}
+
+	// QuoteResult is the bid of a pair. Cached is set when the server answered from its
+	// cache and Stale when it fell back to a stored quote; Age is how old the quote is.
+	QuoteResult struct {
+		Pair      string
+		Bid       string
+		Cached    bool
+		Stale     bool
+		Age       time.Duration
+		Timestamp int64
+	}
+
+	// HistoryFilter selects stored quotes; zero values are left to the server defaults.
+	HistoryFilter struct {
+		Pair   string
+		From   time.Time
+		To     time.Time
+		Limit  int
+		Offset int
+	}
+
+	// CandleFilter selects OHLC candles; Interval is one of 1m, 5m, 15m, 1h, 4h and 1d.
+	CandleFilter struct {
+		Pair     string
+		Interval string
+		From     time.Time
+		To       time.Time
+		Limit    int
+	}
+
+	Candle struct {
+		Pair      string    `json:"pair"`
+		Interval  string    `json:"interval"`
+		Start     time.Time `json:"start"`
+		Open      string    `json:"open"`
+		High      string    `json:"high"`
+		Low       string    `json:"low"`
+		Close     string    `json:"close"`
+		AvgSpread string    `json:"avgSpread"`
+		Count     int       `json:"count"`
+	}
+
+	// ExportFilter selects the exported quotes; Format is csv (default), ndjson or json.
+	ExportFilter struct {
+		Format string
+		Pair   string
+		From   time.Time
+		To     time.Time
+	}
+
+	// ConvertRequest converts Amount of From into To; Side is bid (default) or ask.
+	ConvertRequest struct {
+		From   string
+		To     string
+		Amount string
+		Side   string
+	}
+
+	// Conversion is the answer of Convert; Rate is the price of one From in To.
+	Conversion struct {
+		From      string
+		To        string
+		Side      string
+		Amount    string
+		Rate      string
+		Result    string
+		Pairs     []string
+		Cached    bool
+		Stale     bool
+		Timestamp int64
+	}
+
+	// JobStatus is the state of the background poller of one pair.
+	JobStatus struct {
+		Pair                string     `json:"pair"`
+		Schedule            string     `json:"schedule"`
+		Runs                int        `json:"runs"`
+		Failures            int        `json:"failures"`
+		ConsecutiveFailures int        `json:"consecutiveFailures"`
+		LastRun             *time.Time `json:"lastRun,omitempty"`
+		LastSuccess         *time.Time `json:"lastSuccess,omitempty"`
+		LastError           string     `json:"lastError,omitempty"`
+		LastBid             string     `json:"lastBid,omitempty"`
+		NextRun             *time.Time `json:"nextRun,omitempty"`
+	}
+
+	// Rule is an alert rule; Kind is above, below or change.
+	Rule struct {
+		ID            int64     `json:"id"`
+		Pair          string    `json:"pair"`
+		Kind          string    `json:"kind"`
+		Threshold     string    `json:"threshold"`
+		WindowSeconds int64     `json:"windowSeconds,omitempty"`
+		WebhookURL    string    `json:"webhookUrl"`
+		Enabled       bool      `json:"enabled"`
+		CreatedAt     time.Time `json:"createdAt"`
+	}
+
+	// RuleRequest creates or replaces a rule; a nil Enabled enables it.
+	RuleRequest struct {
+		Pair          string `json:"pair"`
+		Kind          string `json:"kind"`
+		Threshold     string `json:"threshold"`
+		WindowSeconds int64  `json:"windowSeconds,omitempty"`
+		WebhookURL    string `json:"webhookUrl"`
+		Enabled       *bool  `json:"enabled,omitempty"`
+	}
+
+	// Delivery is one attempt to deliver a fired rule to its webhook.
+	Delivery struct {
+		ID             int64     `json:"id"`
+		RuleID         int64     `json:"ruleId"`
+		Pair           string    `json:"pair"`
+		Bid            string    `json:"bid"`
+		QuoteTimestamp int64     `json:"quoteTimestamp"`
+		Reason         string    `json:"reason"`
+		Attempt        int       `json:"attempt"`
+		StatusCode     int       `json:"statusCode"`
+		Error          string    `json:"error,omitempty"`
+		Delivered      bool      `json:"delivered"`
+		CreatedAt      time.Time `json:"createdAt"`
+	}
+)
@@ -0,0 +1,217 @@
+package quoteclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultPair = "USD-BRL"
+
+// ErrStreamDropped is returned by Stream when the server dropped a subscriber too slow
+// to keep up.
+var ErrStreamDropped = errors.New("STREAM_DROPPED")
+
+// Quote returns the current bid of pair, DefaultPair when empty.
+func (c *Client) Quote(ctx context.Context, pair string) (*QuoteResult, error) {
+	if pair == "" {
+		pair = DefaultPair
+	}
+	path := "/cotacao/" + url.PathEscape(pair)
+
+	var res struct {
+		Value     *string `json:"value"`
+		Cached    bool    `json:"cached"`
+		Stale     bool    `json:"stale"`
+		AgeMs     int64   `json:"ageMs"`
+		Timestamp int64   `json:"timestamp"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, &res); err != nil {
+		return nil, err
+	}
+	if res.Value == nil {
+		return nil, fmt.Errorf("%w: missing value", ErrBadData)
+	}
+	if _, err := strconv.ParseFloat(*res.Value, 64); err != nil {
+		return nil, fmt.Errorf("%w: value %q is not a number", ErrBadData, *res.Value)
+	}
+
+	return &QuoteResult{
+		Pair:      pair,
+		Bid:       *res.Value,
+		Cached:    res.Cached,
+		Stale:     res.Stale,
+		Age:       time.Duration(res.AgeMs) * time.Millisecond,
+		Timestamp: res.Timestamp,
+	}, nil
+}
+
+// History returns stored quotes, newest first.
+func (c *Client) History(ctx context.Context, filter HistoryFilter) ([]Quote, error) {
+	query := url.Values{}
+	if filter.Pair != "" {
+		query.Set("pair", filter.Pair)
+	}
+	setTime(query, "from", filter.From)
+	setTime(query, "to", filter.To)
+	setInt(query, "limit", filter.Limit)
+	setInt(query, "offset", filter.Offset)
+
+	var res struct {
+		Quotes []Quote `json:"quotes"`
+	}
+	err := c.do(ctx, http.MethodGet, "/cotacao/history", query, nil, &res)
+	return res.Quotes, err
+}
+
+// Candles returns the OHLC candles of the stored bids.
+func (c *Client) Candles(ctx context.Context, filter CandleFilter) ([]Candle, error) {
+	query := url.Values{}
+	if filter.Pair != "" {
+		query.Set("pair", filter.Pair)
+	}
+	if filter.Interval != "" {
+		query.Set("interval", filter.Interval)
+	}
+	setTime(query, "from", filter.From)
+	setTime(query, "to", filter.To)
+	setInt(query, "limit", filter.Limit)
+
+	var res struct {
+		Candles []Candle `json:"candles"`
+	}
+	err := c.do(ctx, http.MethodGet, "/cotacao/ohlc", query, nil, &res)
+	return res.Candles, err
+}
+
+// Convert converts an amount between currencies with the latest quotes.
+func (c *Client) Convert(ctx context.Context, req ConvertRequest) (*Conversion, error) {
+	query := url.Values{"from": {req.From}, "to": {req.To}, "amount": {req.Amount}}
+	if req.Side != "" {
+		query.Set("side", req.Side)
+	}
+
+	var res Conversion
+	if err := c.do(ctx, http.MethodGet, "/convert", query, nil, &res); err != nil {
+		return nil, err
+	}
+	if _, err := strconv.ParseFloat(res.Result, 64); err != nil {
+		return nil, fmt.Errorf("%w: result %q is not a number", ErrBadData, res.Result)
+	}
+	return &res, nil
+}
+
+// SchedulerStatus returns the background pollers of the server, by pair.
+func (c *Client) SchedulerStatus(ctx context.Context) ([]JobStatus, error) {
+	var res struct {
+		Jobs []JobStatus `json:"jobs"`
+	}
+	err := c.do(ctx, http.MethodGet, "/scheduler", nil, nil, &res)
+	return res.Jobs, err
+}
+
+// Export streams the stored quotes, oldest first, into w and returns how many bytes were
+// written. The export is not retried; when it fails midway the server aborts the
+// connection and the error is returned.
+func (c *Client) Export(ctx context.Context, filter ExportFilter, w io.Writer) (int64, error) {
+	query := url.Values{}
+	if filter.Format != "" {
+		query.Set("format", filter.Format)
+	}
+	if filter.Pair != "" {
+		query.Set("pair", filter.Pair)
+	}
+	setTime(query, "from", filter.From)
+	setTime(query, "to", filter.To)
+
+	res, cancel, err := c.open(ctx, "/cotacao/export", query)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+	defer res.Body.Close()
+
+	n, err := io.Copy(w, res.Body)
+	if err != nil {
+		return n, requestError(ctx, err)
+	}
+	return n, nil
+}
+
+// Stream subscribes to the live quotes of pairs, every pair when empty, and calls fn
+// with each of them until ctx is done, fn fails or the server ends the stream. It
+// returns nil when the server ended it, and ErrStreamDropped when the subscriber was
+// dropped for falling behind.
+func (c *Client) Stream(ctx context.Context, pairs []string, fn func(Quote) error) error {
+	query := url.Values{}
+	if len(pairs) > 0 {
+		query.Set("pair", strings.Join(pairs, ","))
+	}
+
+	res, cancel, err := c.open(ctx, "/cotacao/stream", query)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	defer res.Body.Close()
+
+	var event, data string
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		case line == "":
+			switch event {
+			case "quote":
+				var quote Quote
+				if err := json.Unmarshal([]byte(data), &quote); err != nil {
+					return fmt.Errorf("%w: %w", ErrBadData, err)
+				}
+				if err := fn(quote); err != nil {
+					return err
+				}
+			case "dropped":
+				return ErrStreamDropped
+			}
+			event, data = "", ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return requestError(ctx, err)
+	}
+	return ctx.Err()
+}
+
+// open GETs a streamed answer: Config.Timeout only bounds the wait for its headers.
+func (c *Client) open(ctx context.Context, path string, query url.Values) (*http.Response, context.CancelFunc, error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	var timer *time.Timer
+	if c.cfg.Timeout > 0 {
+		timer = time.AfterFunc(c.cfg.Timeout, cancel)
+	}
+
+	res, _, err := c.send(ctx, streamCtx, 0, http.MethodGet, path, query, nil)
+	if timer != nil && !timer.Stop() && ctx.Err() == nil {
+		if err == nil {
+			res.Body.Close()
+		}
+		err = ErrTimeout
+	}
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return res, cancel, nil
+}
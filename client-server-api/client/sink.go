@@ -0,0 +1,235 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/template"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	SinkText   = "text"
+	SinkJSONL  = "jsonl"
+	SinkCSV    = "csv"
+	SinkStdout = "stdout"
+	SinkSQLite = "sqlite"
+
+	ModeAppend    = "append"
+	ModeOverwrite = "overwrite"
+
+	defaultFormat = "{{.Label}}: {{.Bid}}"
+)
+
+// sinkExtensions names the default output file of each sink, cotacao.<extension>.
+var sinkExtensions = map[string]string{
+	SinkText:   "txt",
+	SinkJSONL:  "jsonl",
+	SinkCSV:    "csv",
+	SinkSQLite: "db",
+}
+
+// csvColumns is the header of the CSV sink.
+var csvColumns = []string{"time", "pair", "bid", "quote_timestamp", "stale"}
+
+type (
+	// Record is one quote as written by the sinks. Time is when the client got it and
+	// QuoteTimestamp the unix time of the quote itself, 0 when the server did not send it.
+	Record struct {
+		Time           time.Time `json:"time"`
+		Pair           string    `json:"pair"`
+		Bid            string    `json:"bid"`
+		QuoteTimestamp int64     `json:"quoteTimestamp,omitempty"`
+		Stale          bool      `json:"stale"`
+	}
+
+	// Sink writes records somewhere; Close releases it once the command is done.
+	Sink interface {
+		Write(r Record) error
+		Close() error
+	}
+
+	// fileSink appends every record to path, or in ModeOverwrite replaces the file with
+	// it. encode writes one record, preceded by the header of the format when header
+	// is set.
+	fileSink struct {
+		path   string
+		mode   string
+		encode func(w io.Writer, r Record, header bool) error
+	}
+
+	stdoutSink struct {
+		tmpl *template.Template
+	}
+
+	// sqliteSink inserts into the quote table of a local SQLite file. In ModeOverwrite
+	// the rows of the pair are replaced, within one transaction.
+	sqliteSink struct {
+		db   *sql.DB
+		mode string
+	}
+)
+
+// Label is "Dólar" for USD-BRL, as in the original cotacao.txt, and the pair otherwise.
+func (r Record) Label() string {
+	if r.Pair == defaultPair {
+		return "Dólar"
+	}
+	return r.Pair
+}
+
+// newSink opens the sink of kind writing to path; format is the text/template of the
+// text and stdout sinks, see Record.
+func newSink(kind, path, mode, format string) (Sink, error) {
+	if mode != ModeAppend && mode != ModeOverwrite {
+		return nil, fmt.Errorf("%w: -mode must be %s or %s, got %q", errUsage, ModeAppend, ModeOverwrite, mode)
+	}
+	tmpl, err := template.New("line").Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid -format: %w", errUsage, err)
+	}
+
+	switch kind {
+	case SinkText:
+		return &fileSink{path: path, mode: mode, encode: func(w io.Writer, r Record, header bool) error {
+			return writeLine(w, tmpl, r)
+		}}, nil
+	case SinkJSONL:
+		return &fileSink{path: path, mode: mode, encode: func(w io.Writer, r Record, header bool) error {
+			return json.NewEncoder(w).Encode(r)
+		}}, nil
+	case SinkCSV:
+		return &fileSink{path: path, mode: mode, encode: encodeCSV}, nil
+	case SinkStdout:
+		return &stdoutSink{tmpl: tmpl}, nil
+	case SinkSQLite:
+		return openSQLiteSink(path, mode)
+	default:
+		return nil, fmt.Errorf("%w: unknown -sink %q, use text, jsonl, csv, stdout or sqlite", errUsage, kind)
+	}
+}
+
+func writeLine(w io.Writer, tmpl *template.Template, r Record) error {
+	if err := tmpl.Execute(w, r); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func encodeCSV(w io.Writer, r Record, header bool) error {
+	cw := csv.NewWriter(w)
+	if header {
+		cw.Write(csvColumns)
+	}
+	cw.Write([]string{r.Time.UTC().Format(time.RFC3339), r.Pair, r.Bid, strconv.FormatInt(r.QuoteTimestamp, 10), strconv.FormatBool(r.Stale)})
+	cw.Flush()
+	return cw.Error()
+}
+
+func (s *fileSink) Write(r Record) error {
+	if s.mode == ModeOverwrite {
+		return writeAtomic(s.path, func(w io.Writer) error {
+			return s.encode(w, r, true)
+		})
+	}
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err == nil {
+		err = s.encode(file, r, info.Size() == 0)
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (s *fileSink) Close() error {
+	return nil
+}
+
+// writeAtomic writes to a temporary file next to path and renames it over path once
+// write succeeded, so readers never see a partial file.
+func writeAtomic(path string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = write(tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *stdoutSink) Write(r Record) error {
+	return writeLine(os.Stdout, s.tmpl, r)
+}
+
+func (s *stdoutSink) Close() error {
+	return nil
+}
+
+func openSQLiteSink(path, mode string) (*sqliteSink, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`
+        CREATE TABLE IF NOT EXISTS quote (
+            time            INTEGER NOT NULL,
+            pair            TEXT    NOT NULL,
+            bid             TEXT    NOT NULL,
+            quote_timestamp INTEGER NOT NULL,
+            stale           INTEGER NOT NULL
+        );
+        CREATE INDEX IF NOT EXISTS quote_pair_time ON quote (pair, time);`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("preparing %s: %w", path, err)
+	}
+	return &sqliteSink{db: db, mode: mode}, nil
+}
+
+func (s *sqliteSink) Write(r Record) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if s.mode == ModeOverwrite {
+		if _, err := tx.Exec(`DELETE FROM quote WHERE pair = ?`, r.Pair); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`INSERT INTO quote (time, pair, bid, quote_timestamp, stale) VALUES (?, ?, ?, ?, ?)`,
+		r.Time.Unix(), r.Pair, r.Bid, r.QuoteTimestamp, r.Stale)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteSink) Close() error {
+	return s.db.Close()
+}
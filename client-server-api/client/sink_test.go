@@ -0,0 +1,94 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSinks(t *testing.T) {
+	records := []Record{
+		{Time: time.Unix(1717171200, 0), Pair: "USD-BRL", Bid: "5.35", QuoteTimestamp: 1717171190},
+		{Time: time.Unix(1717171260, 0), Pair: "USD-BRL", Bid: "5.36", QuoteTimestamp: 1717171250, Stale: true},
+	}
+	writeAll := func(t *testing.T, kind, mode, format string) string {
+		path := filepath.Join(t.TempDir(), "out")
+		sink, err := newSink(kind, path, mode, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range records {
+			if err := sink.Write(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	read := func(t *testing.T, path string) string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	t.Run("should append templated lines", func(t *testing.T) {
+		got := read(t, writeAll(t, SinkText, ModeAppend, `{{.Time.UTC.Format "2006-01-02T15:04:05Z07:00"}} {{.Label}}: {{.Bid}}`))
+		want := "2024-05-31T16:00:00Z Dólar: 5.35\n2024-05-31T16:01:00Z Dólar: 5.36\n"
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should write the csv header once", func(t *testing.T) {
+		got := read(t, writeAll(t, SinkCSV, ModeAppend, defaultFormat))
+		want := "time,pair,bid,quote_timestamp,stale\n2024-05-31T16:00:00Z,USD-BRL,5.35,1717171190,false\n2024-05-31T16:01:00Z,USD-BRL,5.36,1717171250,true\n"
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should keep only the latest record when overwriting", func(t *testing.T) {
+		path := writeAll(t, SinkJSONL, ModeOverwrite, defaultFormat)
+		got := read(t, path)
+		if strings.Count(got, "\n") != 1 || !strings.Contains(got, `"bid":"5.36"`) {
+			t.Errorf("got %q", got)
+		}
+		entries, _ := os.ReadDir(filepath.Dir(path))
+		if len(entries) != 1 {
+			t.Errorf("temporary files left behind: %v", entries)
+		}
+	})
+
+	t.Run("should store rows in sqlite", func(t *testing.T) {
+		for mode, want := range map[string]int{ModeAppend: 2, ModeOverwrite: 1} {
+			db, err := sql.Open("sqlite3", writeAll(t, SinkSQLite, mode, defaultFormat))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var count int
+			if err := db.QueryRow(`SELECT COUNT(*) FROM quote WHERE pair = 'USD-BRL'`).Scan(&count); err != nil {
+				t.Fatal(err)
+			}
+			db.Close()
+			if count != want {
+				t.Errorf("%s: %d rows, want %d", mode, count, want)
+			}
+		}
+	})
+
+	t.Run("should reject bad options as usage errors", func(t *testing.T) {
+		for _, args := range [][3]string{{"xml", ModeAppend, defaultFormat}, {SinkText, "truncate", defaultFormat}, {SinkText, ModeAppend, "{{.Bid"}} {
+			if _, err := newSink(args[0], "out", args[1], args[2]); !errors.Is(err, errUsage) {
+				t.Errorf("newSink%v = %v, want errUsage", args, err)
+			}
+		}
+	})
+}
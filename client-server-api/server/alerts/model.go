@@ -0,0 +1,105 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// KindAbove fires when the bid crosses Threshold upwards.
+	KindAbove = "above"
+	// KindBelow fires when the bid crosses Threshold downwards.
+	KindBelow = "below"
+	// KindChange fires when the bid moved more than Threshold percent, either way,
+	// since the oldest quote of the last WindowSeconds.
+	KindChange = "change"
+
+	InvalidRuleError  = "INVALID_ALERT_RULE"
+	RuleNotFoundError = "ALERT_RULE_NOT_FOUND"
+)
+
+var (
+	ErrInvalidRule  = errors.New(InvalidRuleError)
+	ErrRuleNotFound = errors.New(RuleNotFoundError)
+)
+
+type (
+	Rule struct {
+		ID            int64           `json:"id"`
+		Pair          string          `json:"pair"`
+		Kind          string          `json:"kind"`
+		Threshold     decimal.Decimal `json:"threshold"`
+		WindowSeconds int64           `json:"windowSeconds,omitempty"`
+		WebhookURL    string          `json:"webhookUrl"`
+		Enabled       bool            `json:"enabled"`
+		CreatedAt     time.Time       `json:"createdAt"`
+	}
+
+	// Alert is a rule fired by a quote; it is the payload POSTed to the rule webhook.
+	Alert struct {
+		RuleID    int64           `json:"ruleId"`
+		Pair      string          `json:"pair"`
+		Kind      string          `json:"kind"`
+		Threshold decimal.Decimal `json:"threshold"`
+		Bid       decimal.Decimal `json:"bid"`
+		Timestamp int64           `json:"timestamp"`
+		Reason    string          `json:"reason"`
+
+		webhookURL string
+	}
+
+	// Delivery is one attempt to POST an alert to its webhook.
+	Delivery struct {
+		ID             int64           `json:"id"`
+		RuleID         int64           `json:"ruleId"`
+		Pair           string          `json:"pair"`
+		Bid            decimal.Decimal `json:"bid"`
+		QuoteTimestamp int64           `json:"quoteTimestamp"`
+		Reason         string          `json:"reason"`
+		Attempt        int             `json:"attempt"`
+		StatusCode     int             `json:"statusCode"`
+		Error          string          `json:"error,omitempty"`
+		Delivered      bool            `json:"delivered"`
+		CreatedAt      time.Time       `json:"createdAt"`
+	}
+)
+
+// Validate normalizes the rule pair and checks that the rule can be evaluated.
+func (r *Rule) Validate() error {
+	r.Pair = q.NormalizePair(r.Pair)
+	switch {
+	case !q.IsSupportedPair(r.Pair):
+		return fmt.Errorf("%w: %w: %s", ErrInvalidRule, q.ErrUnsupportedPair, r.Pair)
+	case r.Kind != KindAbove && r.Kind != KindBelow && r.Kind != KindChange:
+		return fmt.Errorf("%w: kind must be %s, %s or %s", ErrInvalidRule, KindAbove, KindBelow, KindChange)
+	case !r.Threshold.IsPositive():
+		return fmt.Errorf("%w: threshold must be positive", ErrInvalidRule)
+	case r.Kind == KindChange && r.WindowSeconds <= 0:
+		return fmt.Errorf("%w: a %s rule needs a positive windowSeconds", ErrInvalidRule, KindChange)
+	}
+
+	webhook, err := url.Parse(r.WebhookURL)
+	if err != nil || (webhook.Scheme != "http" && webhook.Scheme != "https") || webhook.Host == "" {
+		return fmt.Errorf("%w: webhookUrl must be an absolute http(s) URL", ErrInvalidRule)
+	}
+	return nil
+}
+
+func newAlert(rule Rule, quote q.DollarQuote, reason string) Alert {
+	return Alert{
+		RuleID:     rule.ID,
+		Pair:       rule.Pair,
+		Kind:       rule.Kind,
+		Threshold:  rule.Threshold,
+		Bid:        quote.Bid,
+		Timestamp:  quote.Timestamp,
+		Reason:     reason,
+		webhookURL: rule.WebhookURL,
+	}
+}
@@ -0,0 +1,231 @@
+package alerts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	mydb "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/database"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/repository"
+)
+
+type (
+	RuleRepository interface {
+		CreateRule(c context.Context, rule Rule) (Rule, error)
+		FindRule(c context.Context, id int64) (Rule, error)
+		// ListRules returns the rules of pair, or every rule when pair is empty.
+		ListRules(c context.Context, pair string) ([]Rule, error)
+		UpdateRule(c context.Context, rule Rule) (Rule, error)
+		DeleteRule(c context.Context, id int64) error
+	}
+
+	DeliveryRepository interface {
+		CreateDelivery(c context.Context, delivery Delivery) error
+		// ListDeliveries returns the newest limit deliveries of the rule.
+		ListDeliveries(c context.Context, ruleID int64, limit int) ([]Delivery, error)
+	}
+
+	// Store keeps rules and deliveries in the alert_rule and alert_delivery tables of a
+	// database opened by the database package. Every call is bounded by timeout.
+	Store struct {
+		db      *sql.DB
+		dialect mydb.Dialect
+		timeout time.Duration
+		now     func() time.Time
+	}
+)
+
+func NewStore(db *sql.DB, dialect mydb.Dialect, timeout time.Duration) *Store {
+	return &Store{
+		db:      db,
+		dialect: dialect,
+		timeout: timeout,
+		now:     time.Now,
+	}
+}
+
+func (s *Store) CreateRule(c context.Context, rule Rule) (Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return Rule{}, err
+	}
+	if rule.CreatedAt.IsZero() {
+		rule.CreatedAt = s.now()
+	}
+	rule.CreatedAt = rule.CreatedAt.Truncate(time.Second).UTC()
+
+	ctx, cancel := context.WithTimeout(c, s.timeout)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, s.dialect.Rebind(`
+    INSERT INTO alert_rule (Pair, Kind, Threshold, WindowSeconds, WebhookURL, Enabled, CreatedAt)
+    VALUES (?, ?, ?, ?, ?, ?, ?)
+    RETURNING Id
+  `), rule.Pair, rule.Kind, rule.Threshold, rule.WindowSeconds, rule.WebhookURL, rule.Enabled, rule.CreatedAt.Unix()).Scan(&rule.ID)
+
+	return rule, operationError(c, ctx, err)
+}
+
+func (s *Store) FindRule(c context.Context, id int64) (Rule, error) {
+	ctx, cancel := context.WithTimeout(c, s.timeout)
+	defer cancel()
+
+	rule, err := scanRule(s.db.QueryRowContext(ctx, s.dialect.Rebind(selectRule+` WHERE Id = ?`), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Rule{}, ErrRuleNotFound
+	}
+
+	return rule, operationError(c, ctx, err)
+}
+
+func (s *Store) ListRules(c context.Context, pair string) ([]Rule, error) {
+	ctx, cancel := context.WithTimeout(c, s.timeout)
+	defer cancel()
+
+	query, args := selectRule, []any{}
+	if pair != "" {
+		query += ` WHERE Pair = ?`
+		args = append(args, pair)
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.Rebind(query+` ORDER BY Id`), args...)
+	if err != nil {
+		return nil, operationError(c, ctx, err)
+	}
+	defer rows.Close()
+
+	rules := []Rule{}
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, operationError(c, ctx, rows.Err())
+}
+
+func (s *Store) UpdateRule(c context.Context, rule Rule) (Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return Rule{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(c, s.timeout)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, s.dialect.Rebind(`
+    UPDATE alert_rule
+    SET Pair = ?, Kind = ?, Threshold = ?, WindowSeconds = ?, WebhookURL = ?, Enabled = ?
+    WHERE Id = ?
+  `), rule.Pair, rule.Kind, rule.Threshold, rule.WindowSeconds, rule.WebhookURL, rule.Enabled, rule.ID)
+	if err := affectedOne(result, err); err != nil {
+		return Rule{}, operationError(c, ctx, err)
+	}
+
+	return s.FindRule(c, rule.ID)
+}
+
+func (s *Store) DeleteRule(c context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(c, s.timeout)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, s.dialect.Rebind(`DELETE FROM alert_rule WHERE Id = ?`), id)
+	return operationError(c, ctx, affectedOne(result, err))
+}
+
+func (s *Store) CreateDelivery(c context.Context, delivery Delivery) error {
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = s.now()
+	}
+
+	ctx, cancel := context.WithTimeout(c, s.timeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, s.dialect.Rebind(`
+    INSERT INTO alert_delivery (RuleId, Pair, Bid, QuoteTimestamp, Reason, Attempt, StatusCode, Error, Delivered, CreatedAt)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+  `), delivery.RuleID, delivery.Pair, delivery.Bid, delivery.QuoteTimestamp, delivery.Reason, delivery.Attempt, delivery.StatusCode, delivery.Error, delivery.Delivered, delivery.CreatedAt.Unix())
+
+	return operationError(c, ctx, err)
+}
+
+func (s *Store) ListDeliveries(c context.Context, ruleID int64, limit int) ([]Delivery, error) {
+	ctx, cancel := context.WithTimeout(c, s.timeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.Rebind(`
+    SELECT Id, RuleId, Pair, Bid, QuoteTimestamp, Reason, Attempt, StatusCode, Error, Delivered, CreatedAt
+    FROM alert_delivery
+    WHERE RuleId = ?
+    ORDER BY Id DESC
+    LIMIT ?
+  `), ruleID, limit)
+	if err != nil {
+		return nil, operationError(c, ctx, err)
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var (
+			delivery  Delivery
+			createdAt int64
+		)
+		err := rows.Scan(&delivery.ID, &delivery.RuleID, &delivery.Pair, &delivery.Bid, &delivery.QuoteTimestamp, &delivery.Reason, &delivery.Attempt, &delivery.StatusCode, &delivery.Error, &delivery.Delivered, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+		delivery.CreatedAt = time.Unix(createdAt, 0).UTC()
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, operationError(c, ctx, rows.Err())
+}
+
+const selectRule = `
+    SELECT Id, Pair, Kind, Threshold, WindowSeconds, WebhookURL, Enabled, CreatedAt
+    FROM alert_rule`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRule(row scanner) (Rule, error) {
+	var (
+		rule      Rule
+		createdAt int64
+	)
+	err := row.Scan(&rule.ID, &rule.Pair, &rule.Kind, &rule.Threshold, &rule.WindowSeconds, &rule.WebhookURL, &rule.Enabled, &createdAt)
+	rule.CreatedAt = time.Unix(createdAt, 0).UTC()
+	return rule, err
+}
+
+func affectedOne(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+// operationError maps the error of an operation run within ctx, derived from c, the
+// same way the quote repository does.
+func operationError(c, ctx context.Context, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case c.Err() != nil:
+		return c.Err()
+	case ctx.Err() != nil:
+		return repository.ErrTimeout
+	default:
+		return err
+	}
+}
@@ -0,0 +1,123 @@
+package alerts
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mydb "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/database"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+var d = decimal.RequireFromString
+
+func newTestStore(t *testing.T) *Store {
+	db, err := mydb.New(mydb.Config{File: filepath.Join(t.TempDir(), "sqlite.s3db"), RunMigration: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store := NewStore(db.GetConnection(), db.Dialect(), time.Second)
+	store.now = func() time.Time { return time.Unix(1717171200, 0) }
+	return store
+}
+
+func newRule(pair, kind, threshold string) Rule {
+	return Rule{Pair: pair, Kind: kind, Threshold: d(threshold), WebhookURL: "http://localhost:9000/hook", Enabled: true}
+}
+
+func TestStoreRules(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should create, find, update and delete rule", func(t *testing.T) {
+		store := newTestStore(t)
+
+		created, err := store.CreateRule(ctx, newRule("usd-brl", KindAbove, "5.50"))
+		assert.NoError(t, err)
+		assert.NotZero(t, created.ID)
+		assert.Equal(t, "USD-BRL", created.Pair)
+		assert.Equal(t, time.Unix(1717171200, 0).UTC(), created.CreatedAt)
+
+		found, err := store.FindRule(ctx, created.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, created.Kind, found.Kind)
+		assert.True(t, found.Threshold.Equal(d("5.5")))
+		assert.True(t, found.Enabled)
+
+		found.Kind = KindChange
+		found.Threshold = d("2")
+		found.WindowSeconds = 3600
+		found.Enabled = false
+		updated, err := store.UpdateRule(ctx, found)
+		assert.NoError(t, err)
+		assert.Equal(t, KindChange, updated.Kind)
+		assert.Equal(t, int64(3600), updated.WindowSeconds)
+		assert.False(t, updated.Enabled)
+
+		assert.NoError(t, store.DeleteRule(ctx, created.ID))
+		_, err = store.FindRule(ctx, created.ID)
+		assert.ErrorIs(t, err, ErrRuleNotFound)
+		assert.ErrorIs(t, store.DeleteRule(ctx, created.ID), ErrRuleNotFound)
+	})
+
+	t.Run("should list rules by pair", func(t *testing.T) {
+		store := newTestStore(t)
+		for _, rule := range []Rule{newRule("USD-BRL", KindAbove, "5.5"), newRule("EUR-BRL", KindBelow, "6"), newRule("USD-BRL", KindBelow, "5")} {
+			_, err := store.CreateRule(ctx, rule)
+			assert.NoError(t, err)
+		}
+
+		rules, err := store.ListRules(ctx, "USD-BRL")
+		assert.NoError(t, err)
+		assert.Len(t, rules, 2)
+
+		rules, err = store.ListRules(ctx, "")
+		assert.NoError(t, err)
+		assert.Len(t, rules, 3)
+	})
+
+	t.Run("should reject invalid rules", func(t *testing.T) {
+		store := newTestStore(t)
+		invalid := []Rule{
+			newRule("XYZ-BRL", KindAbove, "5"),
+			newRule("USD-BRL", "sideways", "5"),
+			newRule("USD-BRL", KindAbove, "0"),
+			newRule("USD-BRL", KindChange, "2"),
+			{Pair: "USD-BRL", Kind: KindAbove, Threshold: d("5"), WebhookURL: "ftp://example.com"},
+		}
+		for _, rule := range invalid {
+			_, err := store.CreateRule(ctx, rule)
+			assert.ErrorIs(t, err, ErrInvalidRule, rule)
+		}
+
+		_, err := store.UpdateRule(ctx, Rule{ID: 99, Pair: "USD-BRL", Kind: KindAbove, Threshold: d("5"), WebhookURL: "http://localhost/hook"})
+		assert.ErrorIs(t, err, ErrRuleNotFound)
+	})
+}
+
+func TestStoreDeliveries(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		assert.NoError(t, store.CreateDelivery(ctx, Delivery{RuleID: 1, Pair: "USD-BRL", Bid: d("5.51"), QuoteTimestamp: 1717171200, Reason: "crossed", Attempt: attempt, StatusCode: 500, Error: "webhook answered 500"}))
+	}
+	assert.NoError(t, store.CreateDelivery(ctx, Delivery{RuleID: 2, Pair: "EUR-BRL", Bid: d("6"), Attempt: 1, StatusCode: 200, Delivered: true}))
+
+	deliveries, err := store.ListDeliveries(ctx, 1, 2)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 2)
+	assert.Equal(t, 3, deliveries[0].Attempt)
+	assert.Equal(t, 2, deliveries[1].Attempt)
+	assert.Equal(t, "webhook answered 500", deliveries[0].Error)
+	assert.False(t, deliveries[0].Delivered)
+
+	deliveries, err = store.ListDeliveries(ctx, 2, 10)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+	assert.True(t, deliveries[0].Delivered)
+}
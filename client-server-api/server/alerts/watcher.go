@@ -0,0 +1,313 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/repository"
+
+	"github.com/shopspring/decimal"
+)
+
+type (
+	Notifier interface {
+		Notify(alert Alert)
+	}
+
+	WatcherConfig struct {
+		// QueueSize bounds the stored quotes waiting to be evaluated; quotes stored while
+		// it is full are not evaluated, and logged.
+		QueueSize int
+		// Timeout bounds the queries of each evaluation.
+		Timeout time.Duration
+	}
+
+	// Watcher is a DollarQuoteRepository that evaluates the alert rules of every quote it
+	// stores and hands the fired alerts to a Notifier. Storing only enqueues the quote
+	// once it is written; a background loop evaluates the queue in order, so rules never
+	// slow the caller down.
+	//
+	// Crossing rules remember the previous bid they saw, so they fire once per crossing;
+	// the first quote seen after a restart, or after the rule was updated, only sets that
+	// bid. A change rule fires at most once per window.
+	Watcher struct {
+		repository.DollarQuoteRepository
+		rules    RuleRepository
+		notifier Notifier
+		cfg      WatcherConfig
+		queue    chan q.DollarQuote
+		done     chan struct{}
+		// ctx is cancelled when Close gives up waiting, abandoning the queue
+		ctx    context.Context
+		cancel context.CancelFunc
+
+		closeMu sync.RWMutex
+		closed  bool
+
+		mu    sync.Mutex
+		state map[int64]*ruleState
+	}
+
+	// ruleState is what the watcher remembers of a rule between quotes; definition is the
+	// rule it was built for.
+	ruleState struct {
+		pair       string
+		definition string
+		lastBid    *decimal.Decimal
+		lastFire   *int64
+	}
+
+	// watchedRules is a RuleRepository that resets the state of the rules it updates or
+	// deletes.
+	watchedRules struct {
+		RuleRepository
+		watcher *Watcher
+	}
+)
+
+func NewWatcher(repo repository.DollarQuoteRepository, rules RuleRepository, notifier Notifier, cfg WatcherConfig) *Watcher {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1024
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Watcher{
+		DollarQuoteRepository: repo,
+		rules:                 rules,
+		notifier:              notifier,
+		cfg:                   cfg,
+		queue:                 make(chan q.DollarQuote, cfg.QueueSize),
+		done:                  make(chan struct{}),
+		ctx:                   ctx,
+		cancel:                cancel,
+		state:                 map[int64]*ruleState{},
+	}
+	go w.run()
+
+	return w
+}
+
+func (w *Watcher) CreateDollarQuote(c context.Context, quote q.DollarQuote, t time.Duration) error {
+	if err := w.DollarQuoteRepository.CreateDollarQuote(c, quote, t); err != nil {
+		return err
+	}
+
+	w.enqueue(quote)
+	return nil
+}
+
+func (w *Watcher) CreateDollarQuotes(c context.Context, quotes []q.DollarQuote, t time.Duration) error {
+	if err := w.DollarQuoteRepository.CreateDollarQuotes(c, quotes, t); err != nil {
+		return err
+	}
+
+	for _, quote := range quotes {
+		w.enqueue(quote)
+	}
+	return nil
+}
+
+// Rules returns rules wrapped so that updating or deleting a rule through them resets
+// what the watcher remembers of it; rule changes must go through it.
+func (w *Watcher) Rules() RuleRepository {
+	return &watchedRules{RuleRepository: w.rules, watcher: w}
+}
+
+// Close stops accepting quotes and waits for the queued ones to be evaluated. When ctx
+// ends first, the quotes left are not evaluated.
+func (w *Watcher) Close(ctx context.Context) error {
+	w.closeMu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.closeMu.Unlock()
+
+	select {
+	case <-w.done:
+		w.cancel()
+		return nil
+	case <-ctx.Done():
+		log.Printf("alert watcher closed with %d quotes not evaluated", len(w.queue))
+		w.cancel()
+		<-w.done
+		return ctx.Err()
+	}
+}
+
+func (w *Watcher) enqueue(quote q.DollarQuote) {
+	w.closeMu.RLock()
+	defer w.closeMu.RUnlock()
+
+	if w.closed {
+		log.Printf("alerts not evaluated for %s quote from %d: watcher closed", quote.Pair(), quote.Timestamp)
+		return
+	}
+
+	select {
+	case w.queue <- quote:
+	default:
+		log.Printf("alerts not evaluated for %s quote from %d: queue full", quote.Pair(), quote.Timestamp)
+	}
+}
+
+func (w *Watcher) run() {
+	defer close(w.done)
+
+	for quote := range w.queue {
+		if w.ctx.Err() != nil {
+			continue
+		}
+		w.Evaluate(quote)
+	}
+}
+
+// Evaluate checks the enabled rules of the quote pair against it, and forgets the rules
+// of the pair that were deleted or disabled since.
+func (w *Watcher) Evaluate(quote q.DollarQuote) {
+	ctx, cancel := context.WithTimeout(w.ctx, w.cfg.Timeout)
+	defer cancel()
+
+	rules, err := w.rules.ListRules(ctx, quote.Pair())
+	if err != nil {
+		log.Printf("failed to load alert rules of %s: %v", quote.Pair(), err)
+		return
+	}
+
+	enabled := make(map[int64]bool, len(rules))
+	for _, rule := range rules {
+		if rule.Enabled {
+			enabled[rule.ID] = true
+		}
+	}
+	w.prune(quote.Pair(), enabled)
+
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+
+		reason, fired, err := w.check(ctx, rule, quote)
+		if err != nil {
+			log.Printf("failed to evaluate alert rule %d: %v", rule.ID, err)
+			continue
+		}
+		if fired {
+			log.Printf("alert rule %d fired: %s", rule.ID, reason)
+			w.notifier.Notify(newAlert(rule, quote, reason))
+		}
+	}
+}
+
+func (w *Watcher) check(ctx context.Context, rule Rule, quote q.DollarQuote) (string, bool, error) {
+	switch rule.Kind {
+	case KindAbove, KindBelow:
+		w.mu.Lock()
+		state := w.stateOf(rule)
+		previous := state.lastBid
+		bid := quote.Bid
+		state.lastBid = &bid
+		w.mu.Unlock()
+
+		if previous == nil {
+			return "", false, nil
+		}
+		if rule.Kind == KindAbove && previous.LessThanOrEqual(rule.Threshold) && quote.Bid.GreaterThan(rule.Threshold) {
+			return fmt.Sprintf("%s bid %s crossed above %s", rule.Pair, quote.Bid, rule.Threshold), true, nil
+		}
+		if rule.Kind == KindBelow && previous.GreaterThanOrEqual(rule.Threshold) && quote.Bid.LessThan(rule.Threshold) {
+			return fmt.Sprintf("%s bid %s crossed below %s", rule.Pair, quote.Bid, rule.Threshold), true, nil
+		}
+		return "", false, nil
+
+	case KindChange:
+		w.mu.Lock()
+		lastFire := w.stateOf(rule).lastFire
+		w.mu.Unlock()
+		if lastFire != nil && quote.Timestamp-*lastFire < rule.WindowSeconds {
+			return "", false, nil
+		}
+
+		quotes, err := w.ListDollarQuotes(ctx, q.HistoryFilter{
+			Pair:  rule.Pair,
+			From:  time.Unix(quote.Timestamp-rule.WindowSeconds, 0),
+			To:    quote.Time(),
+			Limit: q.MaxHistoryLimit,
+		}, w.cfg.Timeout)
+		if err != nil || len(quotes) == 0 {
+			return "", false, err
+		}
+
+		// quotes are newest first, the reference is the oldest one in the window
+		reference := quotes[len(quotes)-1]
+		if !reference.Bid.IsPositive() {
+			return "", false, nil
+		}
+		change := quote.Bid.Sub(reference.Bid).Div(reference.Bid).Mul(decimal.NewFromInt(100))
+		if change.Abs().LessThanOrEqual(rule.Threshold) {
+			return "", false, nil
+		}
+
+		w.mu.Lock()
+		timestamp := quote.Timestamp
+		w.stateOf(rule).lastFire = &timestamp
+		w.mu.Unlock()
+		return fmt.Sprintf("%s bid moved %s%% in %ds, from %s to %s", rule.Pair, change.Round(2), rule.WindowSeconds, reference.Bid, quote.Bid), true, nil
+	}
+
+	return "", false, nil
+}
+
+// stateOf returns the state of rule, starting over when the rule changed since, which
+// covers an evaluation racing with an update; w.mu must be held.
+func (w *Watcher) stateOf(rule Rule) *ruleState {
+	definition := fmt.Sprintf("%s %s %s %d", rule.Pair, rule.Kind, rule.Threshold, rule.WindowSeconds)
+	state, ok := w.state[rule.ID]
+	if !ok || state.definition != definition {
+		state = &ruleState{pair: rule.Pair, definition: definition}
+		w.state[rule.ID] = state
+	}
+	return state
+}
+
+// prune forgets the rules of pair that are not enabled any more.
+func (w *Watcher) prune(pair string, enabled map[int64]bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for id, state := range w.state {
+		if state.pair == pair && !enabled[id] {
+			delete(w.state, id)
+		}
+	}
+}
+
+func (w *Watcher) forget(id int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.state, id)
+}
+
+func (r *watchedRules) UpdateRule(c context.Context, rule Rule) (Rule, error) {
+	updated, err := r.RuleRepository.UpdateRule(c, rule)
+	if err == nil {
+		r.watcher.forget(updated.ID)
+	}
+	return updated, err
+}
+
+func (r *watchedRules) DeleteRule(c context.Context, id int64) error {
+	err := r.RuleRepository.DeleteRule(c, id)
+	if err == nil {
+		r.watcher.forget(id)
+	}
+	return err
+}
@@ -0,0 +1,232 @@
+package alerts
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/repository"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (r *recorder) Notify(alert Alert) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = append(r.alerts, alert)
+}
+
+func (r *recorder) fired() []Alert {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Alert{}, r.alerts...)
+}
+
+func quote(pair, bid string, timestamp int64) q.DollarQuote {
+	code, codein := pair[:3], pair[4:]
+	return q.DollarQuote{Code: code, Codein: codein, Bid: d(bid), Ask: d(bid), High: d(bid), Low: d(bid), Timestamp: timestamp}
+}
+
+func newTestWatcher(t *testing.T, rules ...Rule) (*Watcher, *recorder) {
+	store := newTestStore(t)
+	for _, rule := range rules {
+		_, err := store.CreateRule(context.Background(), rule)
+		assert.NoError(t, err)
+	}
+
+	notifier := &recorder{}
+	return NewWatcher(repository.NewMemory(), store, notifier, WatcherConfig{Timeout: time.Second}), notifier
+}
+
+func store(t *testing.T, watcher *Watcher, quotes ...q.DollarQuote) {
+	for _, quote := range quotes {
+		assert.NoError(t, watcher.CreateDollarQuote(context.Background(), quote, time.Second))
+	}
+}
+
+// evaluated closes watcher, so every quote stored was evaluated when it returns.
+func evaluated(t *testing.T, watcher *Watcher) {
+	assert.NoError(t, watcher.Close(context.Background()))
+}
+
+// blockingRules is a RuleRepository whose ListRules waits for release.
+type blockingRules struct {
+	RuleRepository
+	release chan struct{}
+}
+
+func (r *blockingRules) ListRules(c context.Context, pair string) ([]Rule, error) {
+	<-r.release
+	return r.RuleRepository.ListRules(c, pair)
+}
+
+func TestWatcher(t *testing.T) {
+	t.Run("should fire once when bid crosses above threshold", func(t *testing.T) {
+		watcher, notifier := newTestWatcher(t, newRule("USD-BRL", KindAbove, "5.50"))
+
+		store(t, watcher,
+			quote("USD-BRL", "5.40", 1),
+			quote("USD-BRL", "5.51", 2),
+			quote("USD-BRL", "5.60", 3),
+		)
+		evaluated(t, watcher)
+
+		fired := notifier.fired()
+		assert.Len(t, fired, 1)
+		assert.Equal(t, "5.51", fired[0].Bid.String())
+		assert.Equal(t, int64(2), fired[0].Timestamp)
+		assert.Equal(t, "USD-BRL bid 5.51 crossed above 5.5", fired[0].Reason)
+	})
+
+	t.Run("should fire again after crossing back", func(t *testing.T) {
+		watcher, notifier := newTestWatcher(t, newRule("USD-BRL", KindBelow, "5"))
+
+		store(t, watcher,
+			quote("USD-BRL", "5.10", 1),
+			quote("USD-BRL", "4.90", 2),
+			quote("USD-BRL", "5.20", 3),
+			quote("USD-BRL", "4.95", 4),
+		)
+		evaluated(t, watcher)
+
+		assert.Len(t, notifier.fired(), 2)
+	})
+
+	t.Run("should not fire on first quote seen", func(t *testing.T) {
+		watcher, notifier := newTestWatcher(t, newRule("USD-BRL", KindAbove, "5"))
+
+		store(t, watcher, quote("USD-BRL", "5.40", 1))
+		evaluated(t, watcher)
+
+		assert.Empty(t, notifier.fired())
+	})
+
+	t.Run("should fire when bid moves more than percent in window", func(t *testing.T) {
+		rule := newRule("USD-BRL", KindChange, "2")
+		rule.WindowSeconds = 600
+		watcher, notifier := newTestWatcher(t, rule)
+
+		store(t, watcher,
+			quote("USD-BRL", "5.00", 1000),
+			quote("USD-BRL", "5.05", 1300),
+			quote("USD-BRL", "5.11", 1500),
+			// still above 2% but within the window of the last alert
+			quote("USD-BRL", "5.20", 1550),
+			// the quotes before 1550 left the window, the reference is 5.20
+			quote("USD-BRL", "4.90", 2150),
+		)
+		evaluated(t, watcher)
+
+		fired := notifier.fired()
+		assert.Len(t, fired, 2)
+		assert.Equal(t, "USD-BRL bid moved 2.2% in 600s, from 5 to 5.11", fired[0].Reason)
+		assert.Equal(t, "USD-BRL bid moved -5.77% in 600s, from 5.2 to 4.9", fired[1].Reason)
+	})
+
+	t.Run("should only evaluate enabled rules of the quote pair", func(t *testing.T) {
+		disabled := newRule("USD-BRL", KindAbove, "5")
+		disabled.Enabled = false
+		watcher, notifier := newTestWatcher(t, disabled, newRule("EUR-BRL", KindAbove, "5"))
+
+		store(t, watcher, quote("USD-BRL", "4.9", 1), quote("USD-BRL", "5.1", 2))
+		evaluated(t, watcher)
+
+		assert.Empty(t, notifier.fired())
+	})
+
+	t.Run("should evaluate batches", func(t *testing.T) {
+		watcher, notifier := newTestWatcher(t, newRule("USD-BRL", KindAbove, "5"))
+
+		err := watcher.CreateDollarQuotes(context.Background(), []q.DollarQuote{quote("USD-BRL", "4.9", 1), quote("USD-BRL", "5.1", 2)}, time.Second)
+		assert.NoError(t, err)
+		evaluated(t, watcher)
+
+		assert.Len(t, notifier.fired(), 1)
+	})
+
+	t.Run("should not wait for evaluation to store", func(t *testing.T) {
+		rules := &blockingRules{RuleRepository: newTestStore(t), release: make(chan struct{})}
+		watcher := NewWatcher(repository.NewMemory(), rules, &recorder{}, WatcherConfig{Timeout: time.Second})
+
+		stored := make(chan struct{})
+		go func() {
+			store(t, watcher, quote("USD-BRL", "5.1", 1), quote("USD-BRL", "5.2", 2))
+			close(stored)
+		}()
+		select {
+		case <-stored:
+		case <-time.After(time.Second):
+			t.Fatal("storing waited for the rules")
+		}
+
+		close(rules.release)
+		evaluated(t, watcher)
+	})
+
+	t.Run("should start over when a rule is updated", func(t *testing.T) {
+		watcher, notifier := newTestWatcher(t, newRule("USD-BRL", KindAbove, "5"))
+		rules := watcher.Rules()
+
+		store(t, watcher, quote("USD-BRL", "4.9", 1))
+		assert.Eventually(t, func() bool { return len(watcher.states()) == 1 }, time.Second, time.Millisecond)
+
+		rule, err := rules.FindRule(context.Background(), 1)
+		assert.NoError(t, err)
+		rule.Threshold = d("6")
+		_, err = rules.UpdateRule(context.Background(), rule)
+		assert.NoError(t, err)
+		assert.Empty(t, watcher.states())
+
+		// 4.9 is forgotten, so 6.1 is the first bid seen by the new rule
+		store(t, watcher, quote("USD-BRL", "6.1", 2), quote("USD-BRL", "5.9", 3), quote("USD-BRL", "6.2", 4))
+		evaluated(t, watcher)
+
+		fired := notifier.fired()
+		assert.Len(t, fired, 1)
+		assert.Equal(t, int64(4), fired[0].Timestamp)
+	})
+
+	t.Run("should forget deleted and disabled rules", func(t *testing.T) {
+		disabled := newRule("USD-BRL", KindBelow, "5")
+		watcher, _ := newTestWatcher(t, newRule("USD-BRL", KindAbove, "5"), disabled)
+		rules := watcher.Rules()
+
+		store(t, watcher, quote("USD-BRL", "4.9", 1))
+		assert.Eventually(t, func() bool { return len(watcher.states()) == 2 }, time.Second, time.Millisecond)
+
+		assert.NoError(t, rules.DeleteRule(context.Background(), 1))
+		assert.Equal(t, []int64{2}, watcher.states())
+
+		rule, err := rules.FindRule(context.Background(), 2)
+		assert.NoError(t, err)
+		rule.Enabled = false
+		// bypass the wrapper, the next evaluation of the pair prunes it
+		_, err = watcher.rules.UpdateRule(context.Background(), rule)
+		assert.NoError(t, err)
+
+		store(t, watcher, quote("USD-BRL", "5.1", 2))
+		evaluated(t, watcher)
+		assert.Empty(t, watcher.states())
+	})
+}
+
+// states returns the ids of the rules the watcher remembers, sorted.
+func (w *Watcher) states() []int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ids := []int64{}
+	for id := range w.state {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,201 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type (
+	DispatcherConfig struct {
+		// QueueSize bounds the alerts waiting for delivery; alerts fired while it is full
+		// are dropped and logged.
+		QueueSize int
+		// Each alert is POSTed up to MaxAttempts times, waiting Backoff and then twice as
+		// long between attempts. Every attempt is bounded by Timeout.
+		MaxAttempts int
+		Backoff     time.Duration
+		Timeout     time.Duration
+		Client      *http.Client
+	}
+
+	// Dispatcher delivers alerts to their webhooks in the background and records every
+	// attempt in a DeliveryRepository. Network errors, 429 and 5xx answers are retried,
+	// any other non 2xx answer is final.
+	Dispatcher struct {
+		cfg        DispatcherConfig
+		deliveries DeliveryRepository
+		queue      chan Alert
+		done       chan struct{}
+		// ctx is cancelled when Close gives up waiting, aborting the POST in flight and
+		// the recording of its delivery
+		ctx    context.Context
+		cancel context.CancelFunc
+
+		mu     sync.RWMutex
+		closed bool
+	}
+)
+
+func NewDispatcher(deliveries DeliveryRepository, cfg DispatcherConfig) *Dispatcher {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 256
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		cfg:        cfg,
+		deliveries: deliveries,
+		queue:      make(chan Alert, cfg.QueueSize),
+		done:       make(chan struct{}),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	go d.run()
+
+	return d
+}
+
+func (d *Dispatcher) Notify(alert Alert) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		log.Printf("dropped alert of rule %d: dispatcher closed", alert.RuleID)
+		return
+	}
+
+	select {
+	case d.queue <- alert:
+	default:
+		log.Printf("dropped alert of rule %d: queue full", alert.RuleID)
+	}
+}
+
+// Close stops accepting alerts and waits for the queued ones to be delivered. When ctx
+// ends first, the POST in flight and the pending retries are abandoned, and no delivery
+// is recorded after Close returns.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-d.done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		log.Printf("alert dispatcher closed with %d alerts not delivered", len(d.queue))
+		d.cancel()
+		<-d.done
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+
+	for alert := range d.queue {
+		if d.ctx.Err() != nil {
+			continue
+		}
+		d.deliver(alert)
+	}
+}
+
+func (d *Dispatcher) deliver(alert Alert) {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		log.Printf("failed to encode alert of rule %d: %v", alert.RuleID, err)
+		return
+	}
+
+	backoff := d.cfg.Backoff
+	for attempt := 1; attempt <= d.cfg.MaxAttempts; attempt++ {
+		status, err := d.post(alert.webhookURL, body)
+		if d.ctx.Err() != nil {
+			log.Printf("abandoned alert of rule %d at shutdown", alert.RuleID)
+			return
+		}
+		delivered := err == nil && status >= 200 && status < 300
+		retry := !delivered && (err != nil || status == http.StatusTooManyRequests || status >= 500)
+		if err == nil && !delivered {
+			err = fmt.Errorf("webhook answered %d", status)
+		}
+
+		d.record(alert, attempt, status, delivered, err)
+		if delivered || !retry || attempt == d.cfg.MaxAttempts {
+			if !delivered {
+				log.Printf("gave up delivering alert of rule %d after %d attempts: %v", alert.RuleID, attempt, err)
+			}
+			return
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-d.ctx.Done():
+			log.Printf("abandoned alert of rule %d at shutdown", alert.RuleID)
+			return
+		}
+		backoff *= 2
+	}
+}
+
+func (d *Dispatcher) post(url string, body []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(d.ctx, d.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := d.cfg.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+
+	return res.StatusCode, nil
+}
+
+func (d *Dispatcher) record(alert Alert, attempt, status int, delivered bool, err error) {
+	delivery := Delivery{
+		RuleID:         alert.RuleID,
+		Pair:           alert.Pair,
+		Bid:            alert.Bid,
+		QuoteTimestamp: alert.Timestamp,
+		Reason:         alert.Reason,
+		Attempt:        attempt,
+		StatusCode:     status,
+		Delivered:      delivered,
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(d.ctx, d.cfg.Timeout)
+	defer cancel()
+
+	if err := d.deliveries.CreateDelivery(ctx, delivery); err != nil {
+		log.Printf("failed to log delivery of rule %d: %v", alert.RuleID, err)
+	}
+}
@@ -0,0 +1,166 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newReceiver answers the first len(statuses) webhook calls with statuses, then 204;
+// every decoded alert is sent on received.
+func newReceiver(t *testing.T, statuses ...int) (*httptest.Server, chan Alert) {
+	calls := &atomic.Int32{}
+	received := make(chan Alert, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert Alert
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&alert))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		received <- alert
+
+		if call := int(calls.Add(1)); call <= len(statuses) {
+			w.WriteHeader(statuses[call-1])
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func newTestAlert(ruleID int64, webhookURL string) Alert {
+	rule := newRule("USD-BRL", KindAbove, "5.5")
+	rule.ID = ruleID
+	rule.WebhookURL = webhookURL
+	return newAlert(rule, quote("USD-BRL", "5.51", 1717171200), "USD-BRL bid 5.51 crossed above 5.5")
+}
+
+func TestDispatcher(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should deliver alert and log it", func(t *testing.T) {
+		receiver, received := newReceiver(t)
+		store := newTestStore(t)
+		dispatcher := NewDispatcher(store, DispatcherConfig{MaxAttempts: 3, Backoff: time.Millisecond})
+
+		dispatcher.Notify(newTestAlert(1, receiver.URL))
+		assert.NoError(t, dispatcher.Close(ctx))
+
+		alert := <-received
+		assert.Equal(t, int64(1), alert.RuleID)
+		assert.Equal(t, "5.51", alert.Bid.String())
+		assert.Equal(t, KindAbove, alert.Kind)
+
+		deliveries, err := store.ListDeliveries(ctx, 1, 10)
+		assert.NoError(t, err)
+		assert.Len(t, deliveries, 1)
+		assert.True(t, deliveries[0].Delivered)
+		assert.Equal(t, http.StatusNoContent, deliveries[0].StatusCode)
+	})
+
+	t.Run("should retry failed deliveries with backoff", func(t *testing.T) {
+		receiver, received := newReceiver(t, http.StatusInternalServerError, http.StatusTooManyRequests)
+		store := newTestStore(t)
+		dispatcher := NewDispatcher(store, DispatcherConfig{MaxAttempts: 5, Backoff: 20 * time.Millisecond})
+
+		start := time.Now()
+		dispatcher.Notify(newTestAlert(1, receiver.URL))
+		assert.NoError(t, dispatcher.Close(ctx))
+
+		assert.Len(t, received, 3)
+		assert.GreaterOrEqual(t, time.Since(start), 60*time.Millisecond)
+
+		deliveries, err := store.ListDeliveries(ctx, 1, 10)
+		assert.NoError(t, err)
+		assert.Len(t, deliveries, 3)
+		assert.True(t, deliveries[0].Delivered)
+		assert.Equal(t, 3, deliveries[0].Attempt)
+		assert.Equal(t, "webhook answered 429", deliveries[1].Error)
+		assert.Equal(t, "webhook answered 500", deliveries[2].Error)
+	})
+
+	t.Run("should give up after max attempts", func(t *testing.T) {
+		receiver, _ := newReceiver(t, 503, 503, 503)
+		store := newTestStore(t)
+		dispatcher := NewDispatcher(store, DispatcherConfig{MaxAttempts: 2, Backoff: time.Millisecond})
+
+		dispatcher.Notify(newTestAlert(1, receiver.URL))
+		assert.NoError(t, dispatcher.Close(ctx))
+
+		deliveries, err := store.ListDeliveries(ctx, 1, 10)
+		assert.NoError(t, err)
+		assert.Len(t, deliveries, 2)
+		assert.False(t, deliveries[0].Delivered)
+	})
+
+	t.Run("should not retry client errors", func(t *testing.T) {
+		receiver, _ := newReceiver(t, http.StatusNotFound)
+		store := newTestStore(t)
+		dispatcher := NewDispatcher(store, DispatcherConfig{MaxAttempts: 5, Backoff: time.Millisecond})
+
+		dispatcher.Notify(newTestAlert(1, receiver.URL))
+		assert.NoError(t, dispatcher.Close(ctx))
+
+		deliveries, err := store.ListDeliveries(ctx, 1, 10)
+		assert.NoError(t, err)
+		assert.Len(t, deliveries, 1)
+		assert.Equal(t, http.StatusNotFound, deliveries[0].StatusCode)
+	})
+
+	t.Run("should log unreachable webhook", func(t *testing.T) {
+		receiver, _ := newReceiver(t)
+		receiver.Close()
+		store := newTestStore(t)
+		dispatcher := NewDispatcher(store, DispatcherConfig{MaxAttempts: 2, Backoff: time.Millisecond})
+
+		dispatcher.Notify(newTestAlert(1, receiver.URL))
+		assert.NoError(t, dispatcher.Close(ctx))
+
+		deliveries, err := store.ListDeliveries(ctx, 1, 10)
+		assert.NoError(t, err)
+		assert.Len(t, deliveries, 2)
+		assert.Zero(t, deliveries[0].StatusCode)
+		assert.NotEmpty(t, deliveries[0].Error)
+	})
+
+	t.Run("should abandon retries when close context ends", func(t *testing.T) {
+		receiver, _ := newReceiver(t, 500, 500, 500)
+		dispatcher := NewDispatcher(newTestStore(t), DispatcherConfig{MaxAttempts: 5, Backoff: time.Hour})
+
+		dispatcher.Notify(newTestAlert(1, receiver.URL))
+
+		closeCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, dispatcher.Close(closeCtx), context.DeadlineExceeded)
+	})
+
+	t.Run("should abort the webhook in flight when close context ends", func(t *testing.T) {
+		posted, release := make(chan struct{}), make(chan struct{})
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(posted)
+			<-release
+		}))
+		t.Cleanup(receiver.Close)
+		t.Cleanup(func() { close(release) })
+		store := newTestStore(t)
+		dispatcher := NewDispatcher(store, DispatcherConfig{MaxAttempts: 3, Timeout: time.Hour})
+
+		dispatcher.Notify(newTestAlert(1, receiver.URL))
+		<-posted
+
+		closeCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		assert.ErrorIs(t, dispatcher.Close(closeCtx), context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+
+		deliveries, err := store.ListDeliveries(ctx, 1, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, deliveries)
+	})
+}
@@ -0,0 +1,328 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/alerts"
+	mydb "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/database"
+	httpserver "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/http"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/provider"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/repository"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/usecase"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/scheduler"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/stream"
+)
+
+type (
+	Config struct {
+		Addr              string `env:"SERVER_ADDR" envDefault:":8080"`
+		ReadTimeoutMS     int    `env:"SERVER_READ_TIMEOUT_MS" envDefault:"5000"`
+		WriteTimeoutMS    int    `env:"SERVER_WRITE_TIMEOUT_MS" envDefault:"10000"`
+		IdleTimeoutMS     int    `env:"SERVER_IDLE_TIMEOUT_MS" envDefault:"60000"`
+		ShutdownTimeoutMS int    `env:"SERVER_SHUTDOWN_TIMEOUT_MS" envDefault:"10000"`
+
+		Driver       string `env:"DB_DRIVER" envDefault:"sqlite"`
+		File         string `env:"DB_FILE" envDefault:"sqlite.s3db"`
+		DSN          string `env:"DB_DSN"`
+		RunMigration bool   `env:"DB_MIGRATION" envDefault:"true"`
+
+		WriteBehind          bool `env:"DB_WRITE_BEHIND" envDefault:"true"`
+		WriteQueueSize       int  `env:"DB_WRITE_QUEUE_SIZE" envDefault:"1024"`
+		WriteBatchSize       int  `env:"DB_WRITE_BATCH_SIZE" envDefault:"100"`
+		WriteFlushIntervalMS int  `env:"DB_WRITE_FLUSH_INTERVAL_MS" envDefault:"1000"`
+		WriteBatchTimeoutMS  int  `env:"DB_WRITE_BATCH_TIMEOUT_MS" envDefault:"1000"`
+		WriteMaxRetries      int  `env:"DB_WRITE_MAX_RETRIES" envDefault:"3"`
+		WriteRetryBackoffMS  int  `env:"DB_WRITE_RETRY_BACKOFF_MS" envDefault:"100"`
+
+		ApiCallTimeoutMS     int     `env:"API_CALL_TIMEOUT_MS" envDefault:"200"`
+		DbOperationTimeoutMS float32 `env:"DB_OPERATION_TIMEOUT_MS" envDefault:"10"`
+		DbQueryTimeoutMS     int     `env:"DB_QUERY_TIMEOUT_MS" envDefault:"1000"`
+
+		ApiURL                   string   `env:"API_URL" envDefault:"https://economia.awesomeapi.com.br/json/"`
+		FrankfurterURL           string   `env:"FRANKFURTER_URL" envDefault:"https://api.frankfurter.app/latest"`
+		Providers                []string `env:"QUOTE_PROVIDERS" envSeparator:"," envDefault:"awesomeapi"`
+		ProviderStrategy         string   `env:"QUOTE_PROVIDER_STRATEGY" envDefault:"failover"`
+		ProviderAttemptTimeoutMS int      `env:"QUOTE_PROVIDER_ATTEMPT_TIMEOUT_MS" envDefault:"0"`
+
+		AlertsEnabled         bool `env:"ALERTS_ENABLED" envDefault:"true"`
+		AlertQueueSize        int  `env:"ALERT_QUEUE_SIZE" envDefault:"256"`
+		AlertEvalQueueSize    int  `env:"ALERT_EVALUATION_QUEUE_SIZE" envDefault:"1024"`
+		AlertWebhookAttempts  int  `env:"ALERT_WEBHOOK_MAX_ATTEMPTS" envDefault:"5"`
+		AlertWebhookBackoffMS int  `env:"ALERT_WEBHOOK_BACKOFF_MS" envDefault:"500"`
+		AlertWebhookTimeoutMS int  `env:"ALERT_WEBHOOK_TIMEOUT_MS" envDefault:"5000"`
+
+		// PollSchedules maps pairs to their polling schedule, a duration or a cron
+		// expression: "USD-BRL=30s;EUR-BRL=*/5 * * * *".
+		PollSchedules    map[string]string `env:"POLL_SCHEDULES" envSeparator:";" envKeyValSeparator:"="`
+		PollJitterMS     int               `env:"POLL_JITTER_MS" envDefault:"1000"`
+		PollBackoffMS    int               `env:"POLL_BACKOFF_MS" envDefault:"1000"`
+		PollMaxBackoffMS int               `env:"POLL_MAX_BACKOFF_MS" envDefault:"300000"`
+
+		StreamBufferSize  int `env:"STREAM_BUFFER_SIZE" envDefault:"16"`
+		StreamHeartbeatMS int `env:"STREAM_HEARTBEAT_MS" envDefault:"15000"`
+
+		ExportWriteTimeoutMS int `env:"EXPORT_WRITE_TIMEOUT_MS" envDefault:"30000"`
+
+		CacheTTLMS       int    `env:"QUOTE_CACHE_TTL_MS" envDefault:"0"`
+		CacheStaleMS     int    `env:"QUOTE_CACHE_STALE_MS" envDefault:"0"`
+		FallbackPolicy   string `env:"QUOTE_FALLBACK" envDefault:"none"`
+		FallbackMaxAgeMS int    `env:"QUOTE_FALLBACK_MAX_AGE_MS" envDefault:"0"`
+	}
+
+	// App wires the quote server together and owns its lifecycle: Run serves until the
+	// context is done, then drains in-flight requests, stops the poller, flushes the
+	// write-behind queue, delivers the pending alerts and closes the database, in that
+	// order.
+	App struct {
+		cfg        Config
+		server     *http.Server
+		scheduler  *scheduler.Scheduler
+		hub        *stream.Hub
+		writer     *repository.Writer
+		watcher    *alerts.Watcher
+		dispatcher *alerts.Dispatcher
+		alertStore *alerts.Store
+		db         *mydb.Client
+	}
+)
+
+func New(ctx context.Context, cfg Config) (*App, error) {
+	a := &App{cfg: cfg}
+
+	schedules, err := parseSchedules(cfg.PollSchedules)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := a.newRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// alerts are evaluated once a quote is actually stored, so the watcher sits below the
+	// write-behind queue
+	if cfg.AlertsEnabled {
+		if a.db == nil {
+			log.Printf("alerts need a database, they are disabled with DB_DRIVER=%s", cfg.Driver)
+		} else {
+			a.alertStore = alerts.NewStore(a.db.GetConnection(), a.db.Dialect(), time.Duration(cfg.DbQueryTimeoutMS)*time.Millisecond)
+			a.dispatcher = alerts.NewDispatcher(a.alertStore, alerts.DispatcherConfig{
+				QueueSize:   cfg.AlertQueueSize,
+				MaxAttempts: cfg.AlertWebhookAttempts,
+				Backoff:     time.Duration(cfg.AlertWebhookBackoffMS) * time.Millisecond,
+				Timeout:     time.Duration(cfg.AlertWebhookTimeoutMS) * time.Millisecond,
+			})
+			a.watcher = alerts.NewWatcher(repo, a.alertStore, a.dispatcher, alerts.WatcherConfig{
+				QueueSize: cfg.AlertEvalQueueSize,
+				Timeout:   time.Duration(cfg.DbQueryTimeoutMS) * time.Millisecond,
+			})
+			repo = a.watcher
+		}
+	}
+
+	// quotes are streamed once they are stored, so the publisher also sits below the
+	// write-behind queue: subscribers never see a quote that history and export miss
+	a.hub = stream.NewHub(cfg.StreamBufferSize)
+	repo = stream.NewPublisher(repo, a.hub)
+
+	if cfg.WriteBehind {
+		a.writer = repository.NewWriter(repo, repository.WriterConfig{
+			QueueSize:     cfg.WriteQueueSize,
+			BatchSize:     cfg.WriteBatchSize,
+			FlushInterval: time.Duration(cfg.WriteFlushIntervalMS) * time.Millisecond,
+			BatchTimeout:  time.Duration(cfg.WriteBatchTimeoutMS) * time.Millisecond,
+			MaxRetries:    cfg.WriteMaxRetries,
+			RetryBackoff:  time.Duration(cfg.WriteRetryBackoffMS) * time.Millisecond,
+		})
+		repo = a.writer
+	}
+
+	quoteProvider, err := provider.New(provider.Config{
+		Names:          cfg.Providers,
+		Strategy:       cfg.ProviderStrategy,
+		AttemptTimeout: time.Duration(cfg.ProviderAttemptTimeoutMS) * time.Millisecond,
+		AwesomeAPIURL:  cfg.ApiURL,
+		FrankfurterURL: cfg.FrankfurterURL,
+	})
+	if err != nil {
+		a.close(ctx)
+		return nil, err
+	}
+	log.Printf("quote provider: %s", quoteProvider.Name())
+
+	uHandler := usecase.NewWithProvider(ctx, repo, quoteProvider, usecase.Config{
+		ApiCallTimeoutMs:     cfg.ApiCallTimeoutMS,
+		DbOperationTimeoutMs: cfg.DbOperationTimeoutMS,
+		DbQueryTimeoutMs:     cfg.DbQueryTimeoutMS,
+		CacheTTLMs:           cfg.CacheTTLMS,
+		CacheStaleMs:         cfg.CacheStaleMS,
+		FallbackPolicy:       cfg.FallbackPolicy,
+		FallbackMaxAgeMs:     cfg.FallbackMaxAgeMS,
+	})
+	a.scheduler = scheduler.New(uHandler, scheduler.Config{
+		Schedules:  schedules,
+		Jitter:     time.Duration(cfg.PollJitterMS) * time.Millisecond,
+		Backoff:    time.Duration(cfg.PollBackoffMS) * time.Millisecond,
+		MaxBackoff: time.Duration(cfg.PollMaxBackoffMS) * time.Millisecond,
+	})
+
+	quoteHandler := httpserver.New(uHandler)
+	quoteHandler.Router.Handle("/debug/vars", expvar.Handler())
+	quoteHandler.Router.Mount("/scheduler", httpserver.NewScheduler(a.scheduler).Router)
+	quoteHandler.Router.Mount("/cotacao/export", httpserver.NewExport(repo, time.Duration(cfg.ExportWriteTimeoutMS)*time.Millisecond).Router)
+	quoteHandler.Router.Mount("/cotacao/stream", httpserver.NewStream(a.hub, time.Duration(cfg.StreamHeartbeatMS)*time.Millisecond).Router)
+	if a.watcher != nil {
+		quoteHandler.Router.Mount("/alerts", httpserver.NewAlerts(a.watcher.Rules(), a.alertStore).Router)
+	}
+
+	a.server = &http.Server{
+		Addr:         cfg.Addr,
+		Handler:      quoteHandler.Router,
+		ReadTimeout:  time.Duration(cfg.ReadTimeoutMS) * time.Millisecond,
+		WriteTimeout: time.Duration(cfg.WriteTimeoutMS) * time.Millisecond,
+		IdleTimeout:  time.Duration(cfg.IdleTimeoutMS) * time.Millisecond,
+	}
+	// streams never end on their own, Shutdown would wait for them until it times out
+	a.server.RegisterOnShutdown(a.hub.Close)
+
+	return a, nil
+}
+
+// Writer returns the write-behind queue, nil when DB_WRITE_BEHIND is off.
+func (a *App) Writer() *repository.Writer {
+	return a.writer
+}
+
+// Hub returns the live quote stream.
+func (a *App) Hub() *stream.Hub {
+	return a.hub
+}
+
+// Run listens on cfg.Addr and serves until ctx is done, see Serve.
+func (a *App) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", a.cfg.Addr)
+	if err != nil {
+		a.close(context.Background())
+		return err
+	}
+
+	return a.Serve(ctx, listener)
+}
+
+// Serve accepts connections on listener until ctx is done, then shuts down within
+// cfg.ShutdownTimeoutMS. It returns nil after a clean shutdown.
+func (a *App) Serve(ctx context.Context, listener net.Listener) error {
+	log.Printf("listening on %s", listener.Addr())
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- a.server.Serve(listener)
+	}()
+	// polling only starts once New succeeded and the server is up
+	a.scheduler.Start()
+
+	select {
+	case err := <-serveErr:
+		a.close(context.Background())
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(a.cfg.ShutdownTimeoutMS)*time.Millisecond)
+	defer cancel()
+
+	err := a.server.Shutdown(shutdownCtx)
+	if err != nil {
+		err = fmt.Errorf("draining requests: %w", err)
+	}
+	if serr := <-serveErr; !errors.Is(serr, http.ErrServerClosed) {
+		err = errors.Join(err, serr)
+	}
+
+	return errors.Join(err, a.close(shutdownCtx))
+}
+
+// close stops the poller, flushes the writer, evaluates and delivers the queued alerts
+// and closes the database.
+func (a *App) close(ctx context.Context) error {
+	var errs []error
+	if a.scheduler != nil {
+		if err := a.scheduler.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stopping poller: %w", err))
+		}
+	}
+	// past the deadline the writer aborts its pending batches, and it only returns once
+	// its loop stopped, so the database is no longer in use below
+	if a.writer != nil {
+		if err := a.writer.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("flushing quotes: %w", err))
+		}
+		log.Printf("quote writer: %+v", a.writer.Stats())
+	}
+	if a.watcher != nil {
+		if err := a.watcher.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("evaluating alerts: %w", err))
+		}
+	}
+	if a.dispatcher != nil {
+		if err := a.dispatcher.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("delivering alerts: %w", err))
+		}
+	}
+	if a.db != nil {
+		if err := a.db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing database: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (a *App) newRepository(ctx context.Context) (repository.DollarQuoteRepository, error) {
+	var err error
+	switch a.cfg.Driver {
+	case "sqlite":
+		a.db, err = mydb.New(mydb.Config{File: a.cfg.File, RunMigration: a.cfg.RunMigration})
+		if err != nil {
+			return nil, err
+		}
+		return repository.New(a.db.GetConnection()), nil
+	case "postgres":
+		a.db, err = mydb.NewPostgres(ctx, mydb.PostgresConfig{DSN: a.cfg.DSN, RunMigration: a.cfg.RunMigration})
+		if err != nil {
+			return nil, err
+		}
+		return repository.NewWithDialect(a.db.GetConnection(), a.db.Dialect()), nil
+	case "memory":
+		return repository.NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown DB_DRIVER %q, use sqlite, postgres or memory", a.cfg.Driver)
+	}
+}
+
+// parseSchedules validates the POLL_SCHEDULES entries, keyed by normalized pair.
+func parseSchedules(specs map[string]string) (map[string]scheduler.Schedule, error) {
+	schedules := make(map[string]scheduler.Schedule, len(specs))
+	for pair, spec := range specs {
+		pair = quotes.NormalizePair(pair)
+		if !quotes.IsSupportedPair(pair) {
+			return nil, fmt.Errorf("POLL_SCHEDULES: %w: %s is not supported, use one of %s", quotes.ErrUnsupportedPair, pair, strings.Join(quotes.SupportedPairs, ", "))
+		}
+
+		schedule, err := scheduler.Parse(spec)
+		if err != nil {
+			return nil, fmt.Errorf("POLL_SCHEDULES %s: %w", pair, err)
+		}
+		schedules[pair] = schedule
+	}
+	return schedules, nil
+}
@@ -0,0 +1,374 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/scheduler"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+const upstreamPayload = `[{"code":"USD","codein":"BRL","name":"Dólar Americano/Real Brasileiro","high":"5.40","low":"5.30","varBid":"0.01","pctChange":"0.2","bid":"5.35","ask":"5.36","timestamp":"1717171200","create_date":"2024-05-31 13:00:00"}]`
+
+// newUpstream answers with upstreamPayload once release is closed; every call is
+// announced on called.
+func newUpstream(t *testing.T, release chan struct{}) (*httptest.Server, chan struct{}) {
+	called := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called <- struct{}{}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		w.Write([]byte(upstreamPayload))
+	}))
+	t.Cleanup(server.Close)
+	return server, called
+}
+
+func testConfig(t *testing.T, upstreamURL string) Config {
+	return Config{
+		ReadTimeoutMS:        1000,
+		WriteTimeoutMS:       5000,
+		IdleTimeoutMS:        1000,
+		ShutdownTimeoutMS:    5000,
+		Driver:               "sqlite",
+		File:                 filepath.Join(t.TempDir(), "sqlite.s3db"),
+		RunMigration:         true,
+		WriteBehind:          true,
+		WriteFlushIntervalMS: int(time.Hour / time.Millisecond),
+		ApiCallTimeoutMS:     5000,
+		DbOperationTimeoutMS: 100,
+		DbQueryTimeoutMS:     1000,
+		ApiURL:               upstreamURL + "/json/",
+		Providers:            []string{"awesomeapi"},
+	}
+}
+
+// start serves a on a random local port and returns its base URL and the Serve result.
+func start(t *testing.T, ctx context.Context, a *App) (string, chan error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- a.Serve(ctx, listener) }()
+
+	return "http://" + listener.Addr().String(), done
+}
+
+func TestShutdown(t *testing.T) {
+	t.Run("should drain in-flight request, flush quotes and close database", func(t *testing.T) {
+		release := make(chan struct{})
+		upstream, called := newUpstream(t, release)
+		cfg := testConfig(t, upstream.URL)
+
+		a, err := New(context.Background(), cfg)
+		assert.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		baseURL, done := start(t, ctx, a)
+
+		type result struct {
+			status int
+			value  *string
+			err    error
+		}
+		requested := make(chan result, 1)
+		go func() {
+			res, err := http.Get(baseURL + "/cotacao")
+			if err != nil {
+				requested <- result{err: err}
+				return
+			}
+			defer res.Body.Close()
+			var body struct{ Value *string }
+			err = json.NewDecoder(res.Body).Decode(&body)
+			requested <- result{status: res.StatusCode, value: body.Value, err: err}
+		}()
+
+		<-called
+		cancel()
+
+		select {
+		case err := <-done:
+			t.Fatalf("server stopped before draining: %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+		_, err = http.Get(baseURL + "/cotacao")
+		assert.Error(t, err, "new connections should be refused while draining")
+
+		close(release)
+		res := <-requested
+		assert.NoError(t, res.err)
+		assert.Equal(t, http.StatusOK, res.status)
+		assert.Equal(t, "5.35", *res.value)
+		assert.NoError(t, <-done)
+
+		assert.Error(t, a.db.GetConnection().Ping(), "database should be closed")
+
+		db, err := sql.Open("sqlite3", cfg.File)
+		assert.NoError(t, err)
+		defer db.Close()
+		var count int
+		assert.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM dollar_quote`).Scan(&count))
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("should give up on requests outlasting the shutdown timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		upstream, called := newUpstream(t, release)
+		cfg := testConfig(t, upstream.URL)
+		cfg.ShutdownTimeoutMS = 50
+
+		a, err := New(context.Background(), cfg)
+		assert.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		baseURL, done := start(t, ctx, a)
+
+		go http.Get(baseURL + "/cotacao")
+		<-called
+		cancel()
+
+		select {
+		case err := <-done:
+			assert.ErrorContains(t, err, "draining requests")
+		case <-time.After(time.Second):
+			t.Fatal("shutdown did not honour its timeout")
+		}
+	})
+
+	t.Run("should fail to run on a busy address", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+
+		cfg := testConfig(t, "http://127.0.0.1:1")
+		cfg.Addr = listener.Addr().String()
+		a, err := New(context.Background(), cfg)
+		assert.NoError(t, err)
+
+		assert.Error(t, a.Run(context.Background()))
+	})
+}
+
+func TestAlerts(t *testing.T) {
+	t.Run("should notify local receiver when bid crosses threshold", func(t *testing.T) {
+		bids := make(chan string, 2)
+		bids <- "5.40"
+		bids <- "5.60"
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bid := <-bids
+			w.Write([]byte(`[{"code":"USD","codein":"BRL","high":"` + bid + `","low":"` + bid + `","bid":"` + bid + `","ask":"` + bid + `","timestamp":"1717171200"}]`))
+		}))
+		t.Cleanup(upstream.Close)
+
+		received := make(chan map[string]any, 1)
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var alert map[string]any
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&alert))
+			received <- alert
+		}))
+		t.Cleanup(receiver.Close)
+
+		cfg := testConfig(t, upstream.URL)
+		cfg.WriteBehind = false
+		cfg.AlertsEnabled = true
+		cfg.AlertWebhookAttempts = 3
+		a, err := New(context.Background(), cfg)
+		assert.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		baseURL, done := start(t, ctx, a)
+
+		rule := `{"pair":"USD-BRL","kind":"above","threshold":"5.50","webhookUrl":"` + receiver.URL + `"}`
+		res, err := http.Post(baseURL+"/alerts", "application/json", strings.NewReader(rule))
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+		for range 2 {
+			res, err := http.Get(baseURL + "/cotacao")
+			assert.NoError(t, err)
+			res.Body.Close()
+		}
+
+		select {
+		case alert := <-received:
+			assert.Equal(t, "5.6", alert["bid"])
+			assert.Equal(t, "above", alert["kind"])
+		case <-time.After(time.Second):
+			t.Fatal("no alert delivered")
+		}
+
+		cancel()
+		assert.NoError(t, <-done)
+	})
+}
+
+func TestPoller(t *testing.T) {
+	t.Run("should store polled quotes, report and export them", func(t *testing.T) {
+		release := make(chan struct{})
+		close(release)
+		upstream, _ := newUpstream(t, release)
+		cfg := testConfig(t, upstream.URL)
+		cfg.WriteBehind = false
+		cfg.PollSchedules = map[string]string{"usd-brl": "20ms"}
+
+		a, err := New(context.Background(), cfg)
+		assert.NoError(t, err)
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, 0, a.scheduler.Status()[0].Runs, "polling starts with Serve")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		baseURL, done := start(t, ctx, a)
+
+		var status struct {
+			Jobs []struct {
+				Pair     string
+				Runs     int
+				LastBid  string
+				Schedule string
+			}
+		}
+		assert.Eventually(t, func() bool {
+			res, err := http.Get(baseURL + "/scheduler")
+			if err != nil {
+				return false
+			}
+			defer res.Body.Close()
+			return json.NewDecoder(res.Body).Decode(&status) == nil && len(status.Jobs) == 1 && status.Jobs[0].Runs >= 2
+		}, 2*time.Second, 10*time.Millisecond)
+		assert.Equal(t, "USD-BRL", status.Jobs[0].Pair)
+		assert.Equal(t, "every 20ms", status.Jobs[0].Schedule)
+		assert.Equal(t, "5.35", status.Jobs[0].LastBid)
+
+		res, err := http.Get(baseURL + "/cotacao/export?format=ndjson&pair=USD-BRL")
+		assert.NoError(t, err)
+		exported, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		assert.NoError(t, err)
+		assert.Equal(t, "application/x-ndjson", res.Header.Get("Content-Type"))
+		assert.GreaterOrEqual(t, strings.Count(string(exported), "\n"), 2)
+
+		cancel()
+		assert.NoError(t, <-done)
+
+		db, err := sql.Open("sqlite3", cfg.File)
+		assert.NoError(t, err)
+		defer db.Close()
+		var count int
+		assert.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM dollar_quote`).Scan(&count))
+		assert.GreaterOrEqual(t, count, 2)
+	})
+}
+
+func TestStream(t *testing.T) {
+	t.Run("should stream stored quotes and end streams at shutdown", func(t *testing.T) {
+		release := make(chan struct{})
+		close(release)
+		upstream, _ := newUpstream(t, release)
+		cfg := testConfig(t, upstream.URL)
+		cfg.WriteFlushIntervalMS = 10
+
+		a, err := New(context.Background(), cfg)
+		assert.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		baseURL, done := start(t, ctx, a)
+
+		res, err := http.Get(baseURL + "/cotacao/stream?pair=USD-BRL")
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+		quote, err := http.Get(baseURL + "/cotacao")
+		assert.NoError(t, err)
+		quote.Body.Close()
+
+		body := bufio.NewReader(res.Body)
+		line, err := body.ReadString('\n')
+		assert.NoError(t, err)
+		assert.Equal(t, "id: 1717171200\n", line)
+
+		history, err := http.Get(baseURL + "/cotacao/history?pair=USD-BRL")
+		assert.NoError(t, err)
+		stored, _ := io.ReadAll(history.Body)
+		history.Body.Close()
+		assert.Contains(t, string(stored), "1717171200")
+
+		cancel()
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("open stream held the shutdown")
+		}
+	})
+}
+
+func TestStreamWriteBehind(t *testing.T) {
+	t.Run("should not stream quotes still waiting to be stored", func(t *testing.T) {
+		release := make(chan struct{})
+		close(release)
+		upstream, _ := newUpstream(t, release)
+		cfg := testConfig(t, upstream.URL)
+
+		a, err := New(context.Background(), cfg)
+		assert.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		baseURL, done := start(t, ctx, a)
+
+		res, err := http.Get(baseURL + "/cotacao/stream?pair=USD-BRL")
+		assert.NoError(t, err)
+		defer res.Body.Close()
+
+		quote, err := http.Get(baseURL + "/cotacao")
+		assert.NoError(t, err)
+		quote.Body.Close()
+
+		// the flush interval is an hour, so the quote is only stored by the shutdown,
+		// which closes the stream first
+		cancel()
+		assert.NoError(t, <-done)
+		events, err := io.ReadAll(res.Body)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(events), "1717171200")
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Run("should reject unknown driver", func(t *testing.T) {
+		cfg := testConfig(t, "http://127.0.0.1:1")
+		cfg.Driver = "oracle"
+
+		_, err := New(context.Background(), cfg)
+		assert.ErrorContains(t, err, "unknown DB_DRIVER")
+	})
+
+	t.Run("should reject invalid poll schedules", func(t *testing.T) {
+		cfg := testConfig(t, "http://127.0.0.1:1")
+		cfg.PollSchedules = map[string]string{"USD-BRL": "every minute"}
+		_, err := New(context.Background(), cfg)
+		assert.ErrorIs(t, err, scheduler.ErrInvalidSchedule)
+
+		cfg.PollSchedules = map[string]string{"USD-XYZ": "30s"}
+		_, err = New(context.Background(), cfg)
+		assert.ErrorIs(t, err, quotes.ErrUnsupportedPair)
+	})
+}
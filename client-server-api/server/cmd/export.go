@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/app"
+	mydb "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/database"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/export"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/repository"
+)
+
+const exportUsage = `usage: server export [-format csv|ndjson|json] [-pair USD-BRL] [-from date] [-to date] [-o file]
+
+Streams the stored quotes, oldest first, from the database of DB_DRIVER, DB_FILE and
+DB_DSN. Dates are unix seconds, RFC 3339 or YYYY-MM-DD. Without -o the quotes go to
+stdout; a file is only replaced once the export succeeded.
+
+`
+
+// runExport is the export subcommand; it returns the process exit code.
+func runExport(cfg app.Config, args []string) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", export.FormatCSV, "csv, ndjson or json")
+	pair := flags.String("pair", "", "currency pair, every pair when empty")
+	from := flags.String("from", "", "first quote time")
+	to := flags.String("to", "", "last quote time")
+	output := flags.String("o", "", "output file, stdout when empty")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), exportUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	filter := quotes.ExportFilter{Pair: quotes.NormalizePair(*pair)}
+	var err error
+	if filter.Pair != "" && !quotes.IsSupportedPair(filter.Pair) {
+		log.Printf("unsupported pair %s", filter.Pair)
+		return 2
+	}
+	if filter.From, err = quotes.ParseTime(*from); err != nil {
+		log.Printf("invalid -from: %v", err)
+		return 2
+	}
+	if filter.To, err = quotes.ParseTime(*to); err != nil {
+		log.Printf("invalid -to: %v", err)
+		return 2
+	}
+	if _, ok := export.Formats[*format]; !ok {
+		log.Printf("unsupported format %q, use csv, ndjson or json", *format)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := openExportDB(ctx, cfg)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	defer db.Close()
+	repo := repository.NewWithDialect(db.GetConnection(), db.Dialect())
+
+	count, err := exportTo(ctx, repo, filter, *format, *output)
+	if err != nil {
+		log.Printf("export failed after %d quotes: %v", count, err)
+		return 1
+	}
+	log.Printf("exported %d quotes", count)
+	return 0
+}
+
+func openExportDB(ctx context.Context, cfg app.Config) (*mydb.Client, error) {
+	switch cfg.Driver {
+	case "sqlite":
+		if _, err := os.Stat(cfg.File); err != nil {
+			return nil, fmt.Errorf("no database to export: %w", err)
+		}
+		return mydb.New(mydb.Config{File: cfg.File, RunMigration: cfg.RunMigration})
+	case "postgres":
+		return mydb.NewPostgres(ctx, mydb.PostgresConfig{DSN: cfg.DSN, RunMigration: cfg.RunMigration})
+	default:
+		return nil, fmt.Errorf("cannot export from DB_DRIVER %q, use sqlite or postgres", cfg.Driver)
+	}
+}
+
+// exportTo writes to a temporary file next to output and renames it over output once
+// the export is complete, so a failed export leaves the previous file untouched.
+func exportTo(ctx context.Context, repo repository.ExporterDollarQuote, filter quotes.ExportFilter, format, output string) (int, error) {
+	if output == "" {
+		return exportBuffered(ctx, repo, filter, format, os.Stdout)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(output), "."+filepath.Base(output)+".*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	count, err := exportBuffered(ctx, repo, filter, format, tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err != nil {
+		return count, err
+	}
+
+	return count, os.Rename(tmp.Name(), output)
+}
+
+func exportBuffered(ctx context.Context, repo repository.ExporterDollarQuote, filter quotes.ExportFilter, format string, w io.Writer) (int, error) {
+	buffered := bufio.NewWriter(w)
+	count, err := export.Quotes(ctx, repo, filter, buffered, format)
+	if err != nil {
+		return count, err
+	}
+	return count, buffered.Flush()
+}
@@ -2,48 +2,50 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"log"
-	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
-	mydb "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/database"
-	httpserver "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/http"
-	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/repository"
-	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/usecase"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/app"
 
 	"github.com/caarlos0/env/v10"
 	"github.com/joho/godotenv"
 )
 
-type Config struct {
-	File                 string  `env:"DB_FILE" envDefault:"sqlite.s3db"`
-	RunMigration         bool    `env:"DB_MIGRATION" envDefault:"true"`
-	ApiCallTimeoutMS     int     `env:"API_CALL_TIMEOUT_MS" envDefault:"200"`
-	DbOperationTimeoutMS float32 `env:"DB_OPERATION_TIMEOUT_MS" envDefault:"10"`
-}
-
 func main() {
 	err := godotenv.Load()
 	if err != nil {
 		log.Printf("No .env file found")
 	}
 
-	cfg := Config{}
+	cfg := app.Config{}
 	err = env.Parse(&cfg)
 	if err != nil {
 		log.Fatalf("failed to load config: %v", err)
 	}
-	log.Printf("config: %+v", cfg)
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		os.Exit(runExport(cfg, os.Args[2:]))
+	}
+	log.Printf("config: %+v", cfg)
 
-	db, err := mydb.New(ctx, mydb.Config{File: cfg.File, RunMigration: cfg.RunMigration})
+	// the app context outlives the signal one, so requests being drained keep running
+	a, err := app.New(context.Background(), cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if writer := a.Writer(); writer != nil {
+		expvar.Publish("quote_writer", expvar.Func(func() any { return writer.Stats() }))
+	}
+	expvar.Publish("quote_stream", expvar.Func(func() any { return a.Hub().Stats() }))
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	repo := repository.New(ctx, db.GetConnection())
-	uHandler := usecase.New(ctx, repo, usecase.Config{ApiCallTimeoutMs: cfg.ApiCallTimeoutMS, DbOperationTimeoutMs: cfg.DbOperationTimeoutMS})
-	quoteHandler := httpserver.New(uHandler)
-	http.ListenAndServe(":8080", quoteHandler.Router)
+	if err := a.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("server stopped")
 }
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	mydb "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/database"
+
+	"github.com/caarlos0/env/v10"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type Config struct {
+	Driver string `env:"DB_DRIVER" envDefault:"sqlite"`
+	File   string `env:"DB_FILE" envDefault:"sqlite.s3db"`
+	DSN    string `env:"DB_DSN"`
+}
+
+const usage = `usage: migrate [-driver sqlite|postgres] [-file sqlite.s3db] [-dsn postgres://...] <command>
+
+commands:
+  status          list migrations and whether they are applied
+  up              apply every pending migration
+  down [steps]    roll back the last applied migrations (default 1)
+  to <version>    migrate up or down to the given version (0 rolls back everything)
+`
+
+func main() {
+	err := godotenv.Load()
+	if err != nil {
+		log.Printf("No .env file found")
+	}
+
+	cfg := Config{}
+	err = env.Parse(&cfg)
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	flag.StringVar(&cfg.Driver, "driver", cfg.Driver, "database driver, sqlite or postgres")
+	flag.StringVar(&cfg.File, "file", cfg.File, "sqlite database file")
+	flag.StringVar(&cfg.DSN, "dsn", cfg.DSN, "postgres connection string")
+	flag.Usage = func() { fmt.Fprint(flag.CommandLine.Output(), usage) }
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var (
+		migrator *mydb.Migrator
+		target   string
+	)
+	switch cfg.Driver {
+	case "sqlite":
+		db, err := sql.Open("sqlite3", mydb.SQLiteDSN(cfg.File))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer db.Close()
+		migrator, target = mydb.NewMigrator(db, mydb.Migrations), cfg.File
+	case "postgres":
+		db, err := sql.Open("postgres", cfg.DSN)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer db.Close()
+		migrator, target = mydb.NewMigrator(db, mydb.PostgresMigrations).WithDialect(mydb.Postgres), "postgres"
+	default:
+		log.Fatalf("unknown driver %q", cfg.Driver)
+	}
+
+	switch flag.Arg(0) {
+	case "status":
+		err = printStatus(migrator)
+	case "up":
+		err = migrator.Up()
+	case "down":
+		steps := 1
+		if flag.NArg() > 1 {
+			steps, err = strconv.Atoi(flag.Arg(1))
+			if err != nil || steps < 1 {
+				log.Fatalf("invalid steps %q", flag.Arg(1))
+			}
+		}
+		err = migrator.Down(steps)
+	case "to":
+		if flag.NArg() < 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		version, convErr := strconv.Atoi(flag.Arg(1))
+		if convErr != nil {
+			log.Fatalf("invalid version %q", flag.Arg(1))
+		}
+		err = migrator.To(version)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	version, err := migrator.Version()
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%s at schema version %d (latest %d)", target, version, migrator.Latest())
+}
+
+func printStatus(migrator *mydb.Migrator) error {
+	status, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range status {
+		applied := "pending"
+		if s.Applied {
+			applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%4d  %-40s %s\n", s.Version, s.Name, applied)
+	}
+	return nil
+}
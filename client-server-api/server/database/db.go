@@ -1,7 +1,6 @@
 package db
 
 import (
-	"context"
 	"database/sql"
 	"os"
 
@@ -18,13 +17,19 @@ type (
 	}
 
 	Client struct {
-		ctx context.Context
-		db  *sql.DB
-		cfg Config
+		db      *sql.DB
+		cfg     Config
+		dialect Dialect
 	}
 )
 
-func New(ctx context.Context, cfg Config) (*Client, error) {
+// SQLiteDSN is the data source name of the SQLite file. It runs in WAL mode, so long
+// reads such as an export do not lock the writes out, nor the other way around.
+func SQLiteDSN(file string) string {
+	return file + "?_journal_mode=WAL"
+}
+
+func New(cfg Config) (*Client, error) {
 	if _, err := os.Stat(cfg.File); os.IsNotExist(err) {
 		file, err := os.Create(cfg.File)
 		if err != nil {
@@ -33,7 +38,7 @@ func New(ctx context.Context, cfg Config) (*Client, error) {
 		file.Close()
 	}
 
-	db, err := sql.Open("sqlite3", cfg.File)
+	db, err := sql.Open("sqlite3", SQLiteDSN(cfg.File))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -42,9 +47,9 @@ func New(ctx context.Context, cfg Config) (*Client, error) {
 		log.Fatal(err)
 	}
 	return &Client{
-		ctx: ctx,
-		db:  db,
-		cfg: cfg,
+		db:      db,
+		cfg:     cfg,
+		dialect: SQLite,
 	}, nil
 }
 
@@ -52,30 +57,20 @@ func (c *Client) GetConnection() *sql.DB {
 	return c.db
 }
 
+func (c *Client) Dialect() Dialect {
+	return c.dialect
+}
+
 func (c *Client) Close() error {
 	return c.db.Close()
 }
 
+// Migrate brings the schema to the latest version of Migrations.
 func Migrate(cfg Config, db *sql.DB) error {
 	if !cfg.RunMigration {
 		log.Println("skipping migration")
 		return nil
 	}
 	log.Println("running migration")
-	_, err := db.Exec(`
-    CREATE TABLE IF NOT EXISTS dollar_quote (
-      Code TEXT,
-      Codein TEXT,
-      Name TEXT,
-      High TEXT,
-      Low TEXT,
-      VarBid TEXT,
-      PctChange TEXT,
-      Bid TEXT,
-      Ask TEXT,
-      Timestamp TEXT,
-      CreateDate TEXT
-    );
-  `)
-	return err
+	return NewMigrator(db, Migrations).Up()
 }
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrate(t *testing.T) {
+	t.Run("should convert legacy TEXT table", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "sqlite.s3db")
+		legacy, err := sql.Open("sqlite3", file)
+		assert.NoError(t, err)
+		_, err = legacy.Exec(`
+      CREATE TABLE dollar_quote (Code TEXT, Codein TEXT, Name TEXT, High TEXT, Low TEXT, VarBid TEXT, PctChange TEXT, Bid TEXT, Ask TEXT, Timestamp TEXT, CreateDate TEXT);
+      INSERT INTO dollar_quote VALUES ('USD', 'BRL', 'Dólar', '5.40', '5.30', '0.01', '0.2', '5.35', '5.36', '1717171200', '2024-05-31 13:00:00');
+    `)
+		assert.NoError(t, err)
+		assert.NoError(t, legacy.Close())
+
+		client, err := New(Config{File: file, RunMigration: true})
+		assert.NoError(t, err)
+		defer client.Close()
+
+		columns, err := columnTypes(client.GetConnection(), "dollar_quote")
+		assert.NoError(t, err)
+		assert.Equal(t, "REAL", columns["Bid"])
+		assert.Equal(t, "INTEGER", columns["Timestamp"])
+		assert.Equal(t, "TEXT", columns["Pair"])
+
+		var (
+			pair      string
+			bid       float64
+			timestamp int64
+		)
+		err = client.GetConnection().QueryRow(`SELECT Pair, Bid, Timestamp FROM dollar_quote`).Scan(&pair, &bid, &timestamp)
+		assert.NoError(t, err)
+		assert.Equal(t, "USD-BRL", pair)
+		assert.Equal(t, 5.35, bid)
+		assert.Equal(t, int64(1717171200), timestamp)
+	})
+
+	t.Run("should open the file in WAL mode", func(t *testing.T) {
+		client, err := New(Config{File: filepath.Join(t.TempDir(), "sqlite.s3db"), RunMigration: true})
+		assert.NoError(t, err)
+		defer client.Close()
+
+		var mode string
+		assert.NoError(t, client.GetConnection().QueryRow(`PRAGMA journal_mode`).Scan(&mode))
+		assert.Equal(t, "wal", mode)
+	})
+
+	t.Run("should be idempotent", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "sqlite.s3db")
+		for range 2 {
+			client, err := New(Config{File: file, RunMigration: true})
+			assert.NoError(t, err)
+			assert.NoError(t, client.Close())
+		}
+	})
+}
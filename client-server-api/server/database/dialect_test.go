@@ -0,0 +1,14 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRebind(t *testing.T) {
+	query := `SELECT Bid FROM dollar_quote WHERE Pair = ? AND Timestamp >= ? LIMIT ?`
+
+	assert.Equal(t, query, SQLite.Rebind(query))
+	assert.Equal(t, `SELECT Bid FROM dollar_quote WHERE Pair = $1 AND Timestamp >= $2 LIMIT $3`, Postgres.Rebind(query))
+}
@@ -0,0 +1,241 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Migrations is the schema history of the quotes database. Never edit an applied
+// migration, append a new one instead.
+//
+// The first steps are written to also accept databases created before versioning
+// existed, which may already contain some of their changes.
+var Migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_dollar_quote",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+        CREATE TABLE IF NOT EXISTS dollar_quote (
+          Code TEXT,
+          Codein TEXT,
+          Name TEXT,
+          High TEXT,
+          Low TEXT,
+          VarBid TEXT,
+          PctChange TEXT,
+          Bid TEXT,
+          Ask TEXT,
+          Timestamp TEXT,
+          CreateDate TEXT
+        );
+      `)
+			return err
+		},
+		Down: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`DROP TABLE IF EXISTS dollar_quote`)
+			return err
+		},
+	},
+	{
+		Version: 2,
+		Name:    "add_dollar_quote_pair",
+		Up: func(tx *sql.Tx) error {
+			columns, err := columnTypes(tx, "dollar_quote")
+			if err != nil {
+				return err
+			}
+			if _, ok := columns["Pair"]; !ok {
+				if _, err := tx.Exec(`ALTER TABLE dollar_quote ADD COLUMN Pair TEXT`); err != nil {
+					return err
+				}
+			}
+
+			_, err = tx.Exec(`UPDATE dollar_quote SET Pair = Code || '-' || Codein WHERE Pair IS NULL`)
+			return err
+		},
+		Down: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`ALTER TABLE dollar_quote DROP COLUMN Pair`)
+			return err
+		},
+	},
+	{
+		Version: 3,
+		Name:    "numeric_dollar_quote_columns",
+		Up: func(tx *sql.Tx) error {
+			columns, err := columnTypes(tx, "dollar_quote")
+			if err != nil {
+				return err
+			}
+			if columns["Bid"] != "TEXT" {
+				return nil
+			}
+
+			// SQLite cannot change a column type, so the table is rebuilt
+			return rebuildDollarQuote(tx, `
+        Code TEXT NOT NULL,
+        Codein TEXT NOT NULL,
+        Name TEXT,
+        High REAL,
+        Low REAL,
+        VarBid REAL,
+        PctChange REAL,
+        Bid REAL NOT NULL,
+        Ask REAL NOT NULL,
+        Timestamp INTEGER NOT NULL,
+        CreateDate TEXT,
+        Pair TEXT NOT NULL
+      `, `
+        COALESCE(Code, ''),
+        COALESCE(Codein, ''),
+        Name,
+        CAST(High AS REAL),
+        CAST(Low AS REAL),
+        CAST(VarBid AS REAL),
+        CAST(PctChange AS REAL),
+        CAST(COALESCE(Bid, 0) AS REAL),
+        CAST(COALESCE(Ask, 0) AS REAL),
+        CAST(COALESCE(Timestamp, 0) AS INTEGER),
+        CreateDate,
+        COALESCE(Pair, Code || '-' || Codein, '')
+      `)
+		},
+		Down: func(tx *sql.Tx) error {
+			return rebuildDollarQuote(tx, `
+        Code TEXT,
+        Codein TEXT,
+        Name TEXT,
+        High TEXT,
+        Low TEXT,
+        VarBid TEXT,
+        PctChange TEXT,
+        Bid TEXT,
+        Ask TEXT,
+        Timestamp TEXT,
+        CreateDate TEXT,
+        Pair TEXT
+      `, `
+        Code,
+        Codein,
+        Name,
+        CAST(High AS TEXT),
+        CAST(Low AS TEXT),
+        CAST(VarBid AS TEXT),
+        CAST(PctChange AS TEXT),
+        CAST(Bid AS TEXT),
+        CAST(Ask AS TEXT),
+        CAST(Timestamp AS TEXT),
+        CreateDate,
+        Pair
+      `)
+		},
+	},
+	{
+		Version: 4,
+		Name:    "index_dollar_quote_pair_timestamp",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+        CREATE INDEX IF NOT EXISTS dollar_quote_pair_timestamp ON dollar_quote (Pair, Timestamp);
+        CREATE INDEX IF NOT EXISTS dollar_quote_timestamp ON dollar_quote (Timestamp);
+      `)
+			return err
+		},
+		Down: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+        DROP INDEX IF EXISTS dollar_quote_pair_timestamp;
+        DROP INDEX IF EXISTS dollar_quote_timestamp;
+      `)
+			return err
+		},
+	},
+	{
+		Version: 5,
+		Name:    "create_alerts",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+        CREATE TABLE IF NOT EXISTS alert_rule (
+          Id INTEGER PRIMARY KEY AUTOINCREMENT,
+          Pair TEXT NOT NULL,
+          Kind TEXT NOT NULL,
+          Threshold REAL NOT NULL,
+          WindowSeconds INTEGER NOT NULL DEFAULT 0,
+          WebhookURL TEXT NOT NULL,
+          Enabled INTEGER NOT NULL DEFAULT 1,
+          CreatedAt INTEGER NOT NULL
+        );
+        CREATE INDEX IF NOT EXISTS alert_rule_pair ON alert_rule (Pair);
+        CREATE TABLE IF NOT EXISTS alert_delivery (
+          Id INTEGER PRIMARY KEY AUTOINCREMENT,
+          RuleId INTEGER NOT NULL,
+          Pair TEXT NOT NULL,
+          Bid REAL NOT NULL,
+          QuoteTimestamp INTEGER NOT NULL,
+          Reason TEXT NOT NULL,
+          Attempt INTEGER NOT NULL,
+          StatusCode INTEGER NOT NULL,
+          Error TEXT NOT NULL,
+          Delivered INTEGER NOT NULL,
+          CreatedAt INTEGER NOT NULL
+        );
+        CREATE INDEX IF NOT EXISTS alert_delivery_rule ON alert_delivery (RuleId, Id);
+      `)
+			return err
+		},
+		Down: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+        DROP TABLE IF EXISTS alert_delivery;
+        DROP TABLE IF EXISTS alert_rule;
+      `)
+			return err
+		},
+	},
+}
+
+// rebuildDollarQuote recreates dollar_quote with the given column definitions, copying
+// the rows through the given select expressions (one per column, in the same order).
+func rebuildDollarQuote(tx *sql.Tx, columns, selectExpressions string) error {
+	statements := []string{
+		`DROP INDEX IF EXISTS dollar_quote_pair_timestamp`,
+		`DROP INDEX IF EXISTS dollar_quote_timestamp`,
+		fmt.Sprintf(`CREATE TABLE dollar_quote_rebuild (%s)`, columns),
+		fmt.Sprintf(`INSERT INTO dollar_quote_rebuild SELECT %s FROM dollar_quote`, selectExpressions),
+		`DROP TABLE dollar_quote`,
+		`ALTER TABLE dollar_quote_rebuild RENAME TO dollar_quote`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func columnTypes(db querier, table string) (map[string]string, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := map[string]string{}
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			ctype     string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &ctype, &notNull, &dfltValue, &pk); err != nil {
+			return nil, err
+		}
+		columns[name] = ctype
+	}
+
+	return columns, rows.Err()
+}
@@ -0,0 +1,222 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+type (
+	// Migration is one schema step. Up and Down run inside the same transaction that
+	// records the version in schema_migrations.
+	Migration struct {
+		Version int
+		Name    string
+		Up      func(tx *sql.Tx) error
+		Down    func(tx *sql.Tx) error
+	}
+
+	MigrationStatus struct {
+		Version   int
+		Name      string
+		Applied   bool
+		AppliedAt time.Time
+	}
+
+	Migrator struct {
+		db         *sql.DB
+		dialect    Dialect
+		migrations []Migration
+	}
+)
+
+func NewMigrator(db *sql.DB, migrations []Migration) *Migrator {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	return &Migrator{
+		db:         db,
+		dialect:    SQLite,
+		migrations: sorted,
+	}
+}
+
+func (m *Migrator) WithDialect(dialect Dialect) *Migrator {
+	m.dialect = dialect
+	return m
+}
+
+// Latest returns the highest known migration version.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns the current schema version, 0 when nothing was applied.
+func (m *Migrator) Version() (int, error) {
+	if err := m.ensureVersionTable(); err != nil {
+		return 0, err
+	}
+
+	var version int
+	err := m.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up() error {
+	return m.To(m.Latest())
+}
+
+// Down rolls back the given number of applied migrations.
+func (m *Migrator) Down(steps int) error {
+	current, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	target := 0
+	applied := 0
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		if m.migrations[i].Version > current {
+			continue
+		}
+		if applied == steps {
+			target = m.migrations[i].Version
+			break
+		}
+		applied++
+	}
+
+	return m.To(target)
+}
+
+// To migrates up or down until the schema is at the given version.
+func (m *Migrator) To(version int) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	current, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case version > current:
+		for _, migration := range m.migrations {
+			if migration.Version <= current || migration.Version > version {
+				continue
+			}
+			if err := m.apply(migration, true); err != nil {
+				return err
+			}
+		}
+	case version < current:
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if migration.Version > current || migration.Version <= version {
+				continue
+			}
+			if err := m.apply(migration, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Status lists every known migration and whether it is applied.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	if err := m.ensureVersionTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version, appliedAt int64
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[int(version)] = time.Unix(appliedAt, 0)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		status = append(status, MigrationStatus{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+
+	return status, nil
+}
+
+func (m *Migrator) apply(migration Migration, up bool) error {
+	direction, step := "up", migration.Up
+	if !up {
+		direction, step = "down", migration.Down
+	}
+	if step == nil {
+		return fmt.Errorf("migration %d %s has no %s step", migration.Version, migration.Name, direction)
+	}
+	log.Printf("migration %d %s: %s", migration.Version, migration.Name, direction)
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := step(tx); err != nil {
+		return fmt.Errorf("migration %d %s %s: %w", migration.Version, migration.Name, direction, err)
+	}
+
+	if up {
+		_, err = tx.Exec(m.dialect.Rebind(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`), migration.Version, migration.Name, time.Now().Unix())
+	} else {
+		_, err = tx.Exec(m.dialect.Rebind(`DELETE FROM schema_migrations WHERE version = ?`), migration.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *Migrator) find(version int) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) ensureVersionTable() error {
+	_, err := m.db.Exec(`
+    CREATE TABLE IF NOT EXISTS schema_migrations (
+      version INTEGER PRIMARY KEY,
+      name TEXT NOT NULL,
+      applied_at BIGINT NOT NULL
+    );
+  `)
+	return err
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "sqlite.s3db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestMigrator(t *testing.T) {
+	t.Run("should migrate up to latest", func(t *testing.T) {
+		db := openTestDB(t)
+		migrator := NewMigrator(db, Migrations)
+
+		assert.NoError(t, migrator.Up())
+
+		version, err := migrator.Version()
+		assert.NoError(t, err)
+		assert.Equal(t, migrator.Latest(), version)
+
+		columns, err := columnTypes(db, "dollar_quote")
+		assert.NoError(t, err)
+		assert.Equal(t, "REAL", columns["Bid"])
+	})
+
+	t.Run("should roll back and reapply", func(t *testing.T) {
+		db := openTestDB(t)
+		migrator := NewMigrator(db, Migrations)
+		assert.NoError(t, migrator.Up())
+		_, err := db.Exec(`INSERT INTO dollar_quote (Code, Codein, Bid, Ask, Timestamp, Pair) VALUES ('USD', 'BRL', 5.35, 5.36, 1717171200, 'USD-BRL')`)
+		assert.NoError(t, err)
+
+		assert.NoError(t, migrator.Down(migrator.Latest()-2))
+		version, err := migrator.Version()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, version)
+
+		columns, err := columnTypes(db, "dollar_quote")
+		assert.NoError(t, err)
+		assert.Equal(t, "TEXT", columns["Bid"])
+
+		var bid string
+		assert.NoError(t, db.QueryRow(`SELECT Bid FROM dollar_quote`).Scan(&bid))
+		assert.Equal(t, "5.35", bid)
+
+		assert.NoError(t, migrator.To(1))
+		columns, err = columnTypes(db, "dollar_quote")
+		assert.NoError(t, err)
+		assert.NotContains(t, columns, "Pair")
+
+		assert.NoError(t, migrator.Up())
+		var pair string
+		assert.NoError(t, db.QueryRow(`SELECT Pair FROM dollar_quote`).Scan(&pair))
+		assert.Equal(t, "USD-BRL", pair)
+	})
+
+	t.Run("should roll back everything", func(t *testing.T) {
+		db := openTestDB(t)
+		migrator := NewMigrator(db, Migrations)
+		assert.NoError(t, migrator.Up())
+
+		assert.NoError(t, migrator.To(0))
+
+		status, err := migrator.Status()
+		assert.NoError(t, err)
+		for _, s := range status {
+			assert.False(t, s.Applied, s.Name)
+		}
+
+		columns, err := columnTypes(db, "dollar_quote")
+		assert.NoError(t, err)
+		assert.Empty(t, columns)
+	})
+
+	t.Run("should reject unknown version", func(t *testing.T) {
+		migrator := NewMigrator(openTestDB(t), Migrations)
+		assert.Error(t, migrator.To(99))
+	})
+
+	t.Run("should not record a failed migration", func(t *testing.T) {
+		db := openTestDB(t)
+		migrator := NewMigrator(db, []Migration{
+			{Version: 1, Name: "broken", Up: func(tx *sql.Tx) error {
+				_, err := tx.Exec(`CREATE TABLE broken (`)
+				return err
+			}},
+		})
+
+		assert.Error(t, migrator.Up())
+		version, err := migrator.Version()
+		assert.NoError(t, err)
+		assert.Equal(t, 0, version)
+	})
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	_ "github.com/lib/pq"
+)
+
+type PostgresConfig struct {
+	DSN          string `env:"DB_DSN"`
+	RunMigration bool   `env:"DB_MIGRATION" envDefault:"true"`
+}
+
+// NewPostgres opens a Postgres connection and applies PostgresMigrations.
+func NewPostgres(ctx context.Context, cfg PostgresConfig) (*Client, error) {
+	db, err := sql.Open("postgres", cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if cfg.RunMigration {
+		log.Println("running migration")
+		if err := NewMigrator(db, PostgresMigrations).WithDialect(Postgres).Up(); err != nil {
+			db.Close()
+			return nil, err
+		}
+	} else {
+		log.Println("skipping migration")
+	}
+
+	return &Client{
+		db:      db,
+		dialect: Postgres,
+	}, nil
+}
+
+// PostgresMigrations is the Postgres counterpart of Migrations. Postgres never had the
+// legacy TEXT schema, so it starts from the typed table.
+var PostgresMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_dollar_quote",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+        CREATE TABLE IF NOT EXISTS dollar_quote (
+          Code TEXT NOT NULL,
+          Codein TEXT NOT NULL,
+          Name TEXT,
+          High NUMERIC,
+          Low NUMERIC,
+          VarBid NUMERIC,
+          PctChange NUMERIC,
+          Bid NUMERIC NOT NULL,
+          Ask NUMERIC NOT NULL,
+          Timestamp BIGINT NOT NULL,
+          CreateDate TEXT,
+          Pair TEXT NOT NULL
+        );
+      `)
+			return err
+		},
+		Down: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`DROP TABLE IF EXISTS dollar_quote`)
+			return err
+		},
+	},
+	{
+		Version: 2,
+		Name:    "index_dollar_quote_pair_timestamp",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+        CREATE INDEX IF NOT EXISTS dollar_quote_pair_timestamp ON dollar_quote (Pair, Timestamp);
+        CREATE INDEX IF NOT EXISTS dollar_quote_timestamp ON dollar_quote (Timestamp);
+      `)
+			return err
+		},
+		Down: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+        DROP INDEX IF EXISTS dollar_quote_pair_timestamp;
+        DROP INDEX IF EXISTS dollar_quote_timestamp;
+      `)
+			return err
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create_alerts",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+        CREATE TABLE IF NOT EXISTS alert_rule (
+          Id BIGSERIAL PRIMARY KEY,
+          Pair TEXT NOT NULL,
+          Kind TEXT NOT NULL,
+          Threshold NUMERIC NOT NULL,
+          WindowSeconds BIGINT NOT NULL DEFAULT 0,
+          WebhookURL TEXT NOT NULL,
+          Enabled BOOLEAN NOT NULL DEFAULT TRUE,
+          CreatedAt BIGINT NOT NULL
+        );
+        CREATE INDEX IF NOT EXISTS alert_rule_pair ON alert_rule (Pair);
+        CREATE TABLE IF NOT EXISTS alert_delivery (
+          Id BIGSERIAL PRIMARY KEY,
+          RuleId BIGINT NOT NULL,
+          Pair TEXT NOT NULL,
+          Bid NUMERIC NOT NULL,
+          QuoteTimestamp BIGINT NOT NULL,
+          Reason TEXT NOT NULL,
+          Attempt INTEGER NOT NULL,
+          StatusCode INTEGER NOT NULL,
+          Error TEXT NOT NULL,
+          Delivered BOOLEAN NOT NULL,
+          CreatedAt BIGINT NOT NULL
+        );
+        CREATE INDEX IF NOT EXISTS alert_delivery_rule ON alert_delivery (RuleId, Id);
+      `)
+			return err
+		},
+		Down: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+        DROP TABLE IF EXISTS alert_delivery;
+        DROP TABLE IF EXISTS alert_rule;
+      `)
+			return err
+		},
+	},
+}
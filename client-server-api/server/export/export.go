@@ -0,0 +1,193 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/repository"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatJSON   = "json"
+
+	UnsupportedFormatError = "UNSUPPORTED_EXPORT_FORMAT"
+)
+
+var ErrUnsupportedFormat = errors.New(UnsupportedFormatError)
+
+// Formats lists the supported formats with their content type and file extension.
+var Formats = map[string]struct{ ContentType, Extension string }{
+	FormatCSV:    {"text/csv; charset=utf-8", "csv"},
+	FormatNDJSON: {"application/x-ndjson", "ndjson"},
+	FormatJSON:   {"application/json", "json"},
+}
+
+// Columns are the CSV header and the keys of every JSON record, in order. Prices keep
+// their decimal string, time is the Timestamp in RFC 3339 (UTC), so the rows load into
+// typed columns without guessing.
+var Columns = []string{"pair", "code", "codein", "name", "high", "low", "varBid", "pctChange", "bid", "ask", "timestamp", "time", "createDate"}
+
+type (
+	// Encoder writes quotes one at a time; Close writes what the format needs at the
+	// end and must be called even when no quote was written. Nothing reaches the
+	// writer before the first Encode or Close.
+	Encoder interface {
+		Encode(quote q.DollarQuote) error
+		Close() error
+	}
+
+	// Record is a quote flattened into Columns.
+	Record struct {
+		Pair       string `json:"pair"`
+		Code       string `json:"code"`
+		Codein     string `json:"codein"`
+		Name       string `json:"name"`
+		High       string `json:"high"`
+		Low        string `json:"low"`
+		VarBid     string `json:"varBid"`
+		PctChange  string `json:"pctChange"`
+		Bid        string `json:"bid"`
+		Ask        string `json:"ask"`
+		Timestamp  int64  `json:"timestamp"`
+		Time       string `json:"time"`
+		CreateDate string `json:"createDate"`
+	}
+
+	csvEncoder struct {
+		w      *csv.Writer
+		header bool
+	}
+
+	ndjsonEncoder struct {
+		enc *json.Encoder
+	}
+
+	jsonEncoder struct {
+		w     io.Writer
+		count int
+	}
+)
+
+func NewEncoder(w io.Writer, format string) (Encoder, error) {
+	switch format {
+	case FormatCSV:
+		return &csvEncoder{w: csv.NewWriter(w)}, nil
+	case FormatNDJSON:
+		return &ndjsonEncoder{enc: json.NewEncoder(w)}, nil
+	case FormatJSON:
+		return &jsonEncoder{w: w}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q, use csv, ndjson or json", ErrUnsupportedFormat, format)
+	}
+}
+
+func NewRecord(quote q.DollarQuote) Record {
+	return Record{
+		Pair:       quote.Pair(),
+		Code:       quote.Code,
+		Codein:     quote.Codein,
+		Name:       quote.Name,
+		High:       quote.High.String(),
+		Low:        quote.Low.String(),
+		VarBid:     quote.VarBid.String(),
+		PctChange:  quote.PctChange.String(),
+		Bid:        quote.Bid.String(),
+		Ask:        quote.Ask.String(),
+		Timestamp:  quote.Timestamp,
+		Time:       quote.Time().UTC().Format(time.RFC3339),
+		CreateDate: quote.CreateDate,
+	}
+}
+
+func (r Record) values() []string {
+	return []string{r.Pair, r.Code, r.Codein, r.Name, r.High, r.Low, r.VarBid, r.PctChange, r.Bid, r.Ask, strconv.FormatInt(r.Timestamp, 10), r.Time, r.CreateDate}
+}
+
+func (e *csvEncoder) writeHeader() error {
+	if e.header {
+		return nil
+	}
+	e.header = true
+	return e.w.Write(Columns)
+}
+
+func (e *csvEncoder) Encode(quote q.DollarQuote) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	return e.w.Write(NewRecord(quote).values())
+}
+
+func (e *csvEncoder) Close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *ndjsonEncoder) Encode(quote q.DollarQuote) error {
+	return e.enc.Encode(NewRecord(quote))
+}
+
+func (e *ndjsonEncoder) Close() error {
+	return nil
+}
+
+func (e *jsonEncoder) Encode(quote q.DollarQuote) error {
+	record, err := json.Marshal(NewRecord(quote))
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n"
+	if e.count == 0 {
+		separator = "[\n"
+	}
+	e.count++
+
+	if _, err := io.WriteString(e.w, separator); err != nil {
+		return err
+	}
+	_, err = e.w.Write(record)
+	return err
+}
+
+func (e *jsonEncoder) Close() error {
+	end := "\n]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+// Quotes streams the quotes of filter from exporter into w, encoded in format, and returns
+// how many were encoded. Quotes are encoded as they are read, the table is never held in
+// memory; on error the output is truncated.
+func Quotes(ctx context.Context, exporter repository.ExporterDollarQuote, filter q.ExportFilter, w io.Writer, format string) (int, error) {
+	enc, err := NewEncoder(w, format)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = exporter.ExportDollarQuotes(ctx, filter, func(quote q.DollarQuote) error {
+		count++
+		return enc.Encode(quote)
+	})
+	if err != nil {
+		return count, err
+	}
+
+	return count, enc.Close()
+}
@@ -0,0 +1,105 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/repository"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func newQuote(code, bid string, timestamp int64) q.DollarQuote {
+	d := decimal.RequireFromString(bid)
+	return q.DollarQuote{Code: code, Codein: "BRL", Name: code + "/Real, comercial", High: d, Low: d, Bid: d, Ask: d.Add(decimal.RequireFromString("0.01")), Timestamp: timestamp}
+}
+
+func newRepo(t *testing.T) *repository.Memory {
+	repo := repository.NewMemory()
+	err := repo.CreateDollarQuotes(context.Background(), []q.DollarQuote{
+		newQuote("USD", "5.35", 1717257600),
+		newQuote("USD", "5.30", 1717171200),
+		newQuote("EUR", "5.80", 1717200000),
+	}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func TestQuotes(t *testing.T) {
+	t.Run("should export csv with header", func(t *testing.T) {
+		var out bytes.Buffer
+		count, err := Quotes(context.Background(), newRepo(t), q.ExportFilter{}, &out, FormatCSV)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, count)
+
+		rows, err := csv.NewReader(&out).ReadAll()
+		assert.NoError(t, err)
+		assert.Len(t, rows, 4)
+		assert.Equal(t, Columns, rows[0])
+		assert.Equal(t, []string{"USD-BRL", "USD", "BRL", "USD/Real, comercial", "5.3", "5.3", "0", "0", "5.3", "5.31", "1717171200", "2024-05-31T16:00:00Z", ""}, rows[1])
+		assert.Equal(t, "EUR-BRL", rows[2][0])
+	})
+
+	t.Run("should export ndjson filtered", func(t *testing.T) {
+		var out bytes.Buffer
+		count, err := Quotes(context.Background(), newRepo(t), q.ExportFilter{Pair: "USD-BRL", From: time.Unix(1717200000, 0)}, &out, FormatNDJSON)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		var record Record
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &record))
+		assert.Equal(t, "5.35", record.Bid)
+		assert.Equal(t, int64(1717257600), record.Timestamp)
+	})
+
+	t.Run("should export a json array", func(t *testing.T) {
+		var out bytes.Buffer
+		_, err := Quotes(context.Background(), newRepo(t), q.ExportFilter{}, &out, FormatJSON)
+		assert.NoError(t, err)
+
+		var records []Record
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &records))
+		assert.Len(t, records, 3)
+	})
+
+	t.Run("should write empty documents", func(t *testing.T) {
+		for format, want := range map[string]string{
+			FormatCSV:    strings.Join(Columns, ",") + "\n",
+			FormatNDJSON: "",
+			FormatJSON:   "[]\n",
+		} {
+			var out bytes.Buffer
+			count, err := Quotes(context.Background(), repository.NewMemory(), q.ExportFilter{}, &out, format)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, count)
+			assert.Equal(t, want, out.String(), format)
+		}
+	})
+
+	t.Run("should reject unknown format", func(t *testing.T) {
+		_, err := Quotes(context.Background(), newRepo(t), q.ExportFilter{}, &bytes.Buffer{}, "parquet")
+		assert.ErrorIs(t, err, ErrUnsupportedFormat)
+	})
+
+	t.Run("should stop on write error", func(t *testing.T) {
+		count, err := Quotes(context.Background(), newRepo(t), q.ExportFilter{}, failingWriter{}, FormatNDJSON)
+		assert.Error(t, err)
+		assert.Equal(t, 1, count)
+	})
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
@@ -0,0 +1,204 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/alerts"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+)
+
+const defaultDeliveriesLimit = 50
+
+type (
+	alertHandler struct {
+		rules      alerts.RuleRepository
+		deliveries alerts.DeliveryRepository
+		Router     chi.Router
+	}
+
+	// ruleRequest is the body of rule creation and update; Enabled defaults to true.
+	ruleRequest struct {
+		Pair          string          `json:"pair"`
+		Kind          string          `json:"kind"`
+		Threshold     decimal.Decimal `json:"threshold"`
+		WindowSeconds int64           `json:"windowSeconds"`
+		WebhookURL    string          `json:"webhookUrl"`
+		Enabled       *bool           `json:"enabled"`
+	}
+
+	ruleResponse struct {
+		Err  *string
+		Code *string
+		Rule *alerts.Rule
+	}
+
+	rulesResponse struct {
+		Err   *string
+		Code  *string
+		Rules []alerts.Rule
+	}
+
+	deliveriesResponse struct {
+		Err        *string
+		Code       *string
+		Deliveries []alerts.Delivery
+	}
+)
+
+func NewAlerts(rules alerts.RuleRepository, deliveries alerts.DeliveryRepository) *alertHandler {
+	r := chi.NewRouter()
+	handler := &alertHandler{
+		rules:      rules,
+		deliveries: deliveries,
+		Router:     r,
+	}
+	r.Get("/", handler.listRules)
+	r.Post("/", handler.createRule)
+	r.Get("/{id}", handler.getRule)
+	r.Put("/{id}", handler.updateRule)
+	r.Delete("/{id}", handler.deleteRule)
+	r.Get("/{id}/deliveries", handler.listDeliveries)
+	return handler
+}
+
+func (h *alertHandler) listRules(w http.ResponseWriter, r *http.Request) {
+	response := rulesResponse{}
+
+	pair := r.URL.Query().Get("pair")
+	if pair != "" {
+		pair = quotes.NormalizePair(pair)
+	}
+
+	rules, err := h.rules.ListRules(r.Context(), pair)
+	if err != nil {
+		log.Println("Error listing alert rules:", err)
+		response.Err, response.Code = writeError(w, err)
+	}
+	response.Rules = rules
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *alertHandler) createRule(w http.ResponseWriter, r *http.Request) {
+	response := ruleResponse{}
+
+	rule, err := decodeRule(r)
+	if err == nil {
+		rule, err = h.rules.CreateRule(r.Context(), rule)
+	}
+	if err != nil {
+		log.Println("Error creating alert rule:", err)
+		response.Err, response.Code = writeError(w, err)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Rule = &rule
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *alertHandler) getRule(w http.ResponseWriter, r *http.Request) {
+	response := ruleResponse{}
+
+	id, err := ruleID(r)
+	var rule alerts.Rule
+	if err == nil {
+		rule, err = h.rules.FindRule(r.Context(), id)
+	}
+	if err != nil {
+		response.Err, response.Code = writeError(w, err)
+	} else {
+		response.Rule = &rule
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *alertHandler) updateRule(w http.ResponseWriter, r *http.Request) {
+	response := ruleResponse{}
+
+	id, err := ruleID(r)
+	var rule alerts.Rule
+	if err == nil {
+		rule, err = decodeRule(r)
+	}
+	if err == nil {
+		rule.ID = id
+		rule, err = h.rules.UpdateRule(r.Context(), rule)
+	}
+	if err != nil {
+		log.Println("Error updating alert rule:", err)
+		response.Err, response.Code = writeError(w, err)
+	} else {
+		response.Rule = &rule
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *alertHandler) deleteRule(w http.ResponseWriter, r *http.Request) {
+	id, err := ruleID(r)
+	if err == nil {
+		err = h.rules.DeleteRule(r.Context(), id)
+	}
+	if err != nil {
+		response := ruleResponse{}
+		response.Err, response.Code = writeError(w, err)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *alertHandler) listDeliveries(w http.ResponseWriter, r *http.Request) {
+	response := deliveriesResponse{}
+
+	id, err := ruleID(r)
+	limit := defaultDeliveriesLimit
+	if v := r.URL.Query().Get("limit"); err == nil && v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > quotes.MaxHistoryLimit {
+			err = fmt.Errorf("%w: limit must be between 1 and %d", errInvalidParameter, quotes.MaxHistoryLimit)
+		}
+	}
+	if err == nil {
+		_, err = h.rules.FindRule(r.Context(), id)
+	}
+	if err == nil {
+		response.Deliveries, err = h.deliveries.ListDeliveries(r.Context(), id, limit)
+	}
+	if err != nil {
+		response.Err, response.Code = writeError(w, err)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func ruleID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: invalid rule id %q", errInvalidParameter, chi.URLParam(r, "id"))
+	}
+	return id, nil
+}
+
+func decodeRule(r *http.Request) (alerts.Rule, error) {
+	var request ruleRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return alerts.Rule{}, fmt.Errorf("%w: %w", alerts.ErrInvalidRule, err)
+	}
+
+	rule := alerts.Rule{
+		Pair:          request.Pair,
+		Kind:          request.Kind,
+		Threshold:     request.Threshold,
+		WindowSeconds: request.WindowSeconds,
+		WebhookURL:    request.WebhookURL,
+		Enabled:       request.Enabled == nil || *request.Enabled,
+	}
+	return rule, nil
+}
@@ -0,0 +1,116 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/alerts"
+	mydb "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/database"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupAlertsTest(t *testing.T) (http.Handler, *alerts.Store) {
+	db, err := mydb.New(mydb.Config{File: filepath.Join(t.TempDir(), "sqlite.s3db"), RunMigration: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store := alerts.NewStore(db.GetConnection(), db.Dialect(), time.Second)
+	return NewAlerts(store, store).Router, store
+}
+
+func serve(t *testing.T, handler http.Handler, method, url, body string, response any) int {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responseWriter := httptest.NewRecorder()
+	handler.ServeHTTP(responseWriter, req)
+
+	if response != nil {
+		if err := json.NewDecoder(responseWriter.Body).Decode(response); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return responseWriter.Code
+}
+
+func TestAlertRules(t *testing.T) {
+	t.Run("should manage rule lifecycle", func(t *testing.T) {
+		handler, _ := setupAlertsTest(t)
+
+		var created ruleResponse
+		status := serve(t, handler, "POST", "/", `{"pair":"usd-brl","kind":"above","threshold":"5.50","webhookUrl":"http://localhost:9000/hook"}`, &created)
+		assert.Equal(t, http.StatusCreated, status)
+		assert.Nil(t, created.Err)
+		assert.Equal(t, "USD-BRL", created.Rule.Pair)
+		assert.True(t, created.Rule.Enabled)
+		id := created.Rule.ID
+
+		var list rulesResponse
+		assert.Equal(t, http.StatusOK, serve(t, handler, "GET", "/?pair=USD-BRL", "", &list))
+		assert.Len(t, list.Rules, 1)
+
+		var updated ruleResponse
+		status = serve(t, handler, "PUT", "/"+strconv.FormatInt(id, 10), `{"pair":"USD-BRL","kind":"change","threshold":"2","windowSeconds":3600,"webhookUrl":"http://localhost:9000/hook","enabled":false}`, &updated)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, alerts.KindChange, updated.Rule.Kind)
+		assert.False(t, updated.Rule.Enabled)
+
+		var found ruleResponse
+		assert.Equal(t, http.StatusOK, serve(t, handler, "GET", "/"+strconv.FormatInt(id, 10), "", &found))
+		assert.Equal(t, int64(3600), found.Rule.WindowSeconds)
+
+		assert.Equal(t, http.StatusNoContent, serve(t, handler, "DELETE", "/"+strconv.FormatInt(id, 10), "", nil))
+
+		var missing ruleResponse
+		assert.Equal(t, http.StatusNotFound, serve(t, handler, "GET", "/"+strconv.FormatInt(id, 10), "", &missing))
+		assert.Equal(t, alerts.RuleNotFoundError, *missing.Code)
+	})
+
+	t.Run("should reject invalid rule", func(t *testing.T) {
+		handler, _ := setupAlertsTest(t)
+
+		for _, body := range []string{
+			`{"pair":"XYZ-BRL","kind":"above","threshold":"5","webhookUrl":"http://localhost/hook"}`,
+			`{"pair":"USD-BRL","kind":"change","threshold":"2","webhookUrl":"http://localhost/hook"}`,
+			`{"pair":"USD-BRL","kind":"above","threshold":"5","webhookUrl":"not a url"}`,
+			`not json`,
+		} {
+			var response ruleResponse
+			assert.Equal(t, http.StatusBadRequest, serve(t, handler, "POST", "/", body, &response), body)
+			assert.Equal(t, alerts.InvalidRuleError, *response.Code, body)
+		}
+
+		var response ruleResponse
+		assert.Equal(t, http.StatusBadRequest, serve(t, handler, "GET", "/abc", "", &response))
+		assert.Equal(t, InvalidParameterError, *response.Code)
+	})
+
+	t.Run("should list rule deliveries", func(t *testing.T) {
+		handler, store := setupAlertsTest(t)
+		rule, err := store.CreateRule(context.Background(), alerts.Rule{Pair: "USD-BRL", Kind: alerts.KindAbove, Threshold: decimal.RequireFromString("5"), WebhookURL: "http://localhost/hook", Enabled: true})
+		assert.NoError(t, err)
+		for attempt := 1; attempt <= 2; attempt++ {
+			assert.NoError(t, store.CreateDelivery(context.Background(), alerts.Delivery{RuleID: rule.ID, Pair: "USD-BRL", Bid: decimal.RequireFromString("5.1"), Attempt: attempt}))
+		}
+
+		var response deliveriesResponse
+		assert.Equal(t, http.StatusOK, serve(t, handler, "GET", "/"+strconv.FormatInt(rule.ID, 10)+"/deliveries?limit=1", "", &response))
+		assert.Len(t, response.Deliveries, 1)
+		assert.Equal(t, 2, response.Deliveries[0].Attempt)
+
+		assert.Equal(t, http.StatusNotFound, serve(t, handler, "GET", "/99/deliveries", "", &response))
+	})
+}
@@ -0,0 +1,121 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/export"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type (
+	// exportHandler streams stored quotes as a file download. An export outlives the
+	// server write timeout as long as each write takes less than writeTimeout; a failure
+	// after the first quote aborts the connection, so the client sees a truncated body
+	// instead of a complete looking file.
+	exportHandler struct {
+		exporter     repository.ExporterDollarQuote
+		writeTimeout time.Duration
+		Router       chi.Router
+	}
+
+	// deadlineWriter pushes the write deadline of the response before every write.
+	deadlineWriter struct {
+		w       http.ResponseWriter
+		rc      *http.ResponseController
+		timeout time.Duration
+		wrote   bool
+	}
+)
+
+func NewExport(exporter repository.ExporterDollarQuote, writeTimeout time.Duration) *exportHandler {
+	if writeTimeout <= 0 {
+		writeTimeout = 30 * time.Second
+	}
+
+	r := chi.NewRouter()
+	handler := &exportHandler{
+		exporter:     exporter,
+		writeTimeout: writeTimeout,
+		Router:       r,
+	}
+	r.Get("/", handler.exportQuotes)
+	return handler
+}
+
+func (h *exportHandler) exportQuotes(w http.ResponseWriter, r *http.Request) {
+	filter, format, err := parseExportFilter(r)
+	if err != nil {
+		response := response{}
+		response.Err, response.Code = writeError(w, err)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	name := "quotes"
+	if filter.Pair != "" {
+		name += "-" + filter.Pair
+	}
+	w.Header().Set("Content-Type", export.Formats[format].ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, export.Formats[format].Extension))
+
+	out := &deadlineWriter{w: w, rc: http.NewResponseController(w), timeout: h.writeTimeout}
+	count, err := export.Quotes(r.Context(), h.exporter, filter, out, format)
+	if err == nil {
+		return
+	}
+
+	log.Printf("Error exporting quotes after %d rows: %v", count, err)
+	if out.wrote {
+		panic(http.ErrAbortHandler)
+	}
+	w.Header().Del("Content-Disposition")
+	w.Header().Set("Content-Type", "application/json")
+	response := response{}
+	response.Err, response.Code = writeError(w, err)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (d *deadlineWriter) Write(p []byte) (int, error) {
+	d.rc.SetWriteDeadline(time.Now().Add(d.timeout))
+	d.wrote = true
+	return d.w.Write(p)
+}
+
+// parseExportFilter reads format (csv by default), pair, from and to, see
+// quotes.ParseTime.
+func parseExportFilter(r *http.Request) (quotes.ExportFilter, string, error) {
+	query := r.URL.Query()
+	filter := quotes.ExportFilter{Pair: quotes.NormalizePair(query.Get("pair"))}
+
+	format := query.Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+	if _, ok := export.Formats[format]; !ok {
+		return filter, format, fmt.Errorf("%w: %q, use csv, ndjson or json", export.ErrUnsupportedFormat, format)
+	}
+
+	if filter.Pair != "" && !quotes.IsSupportedPair(filter.Pair) {
+		return filter, format, fmt.Errorf("%w: %s", quotes.ErrUnsupportedPair, filter.Pair)
+	}
+
+	var err error
+	if filter.From, err = quotes.ParseTime(query.Get("from")); err != nil {
+		return filter, format, fmt.Errorf("%w: invalid from: %w", errInvalidParameter, err)
+	}
+	if filter.To, err = quotes.ParseTime(query.Get("to")); err != nil {
+		return filter, format, fmt.Errorf("%w: invalid to: %w", errInvalidParameter, err)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		return filter, format, fmt.Errorf("%w: from must not be after to", errInvalidParameter)
+	}
+
+	return filter, format, nil
+}
@@ -0,0 +1,90 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/export"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/repository"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// brokenExporter hands over e.quotes quotes, then fails.
+type brokenExporter struct {
+	quotes int
+}
+
+func (e brokenExporter) ExportDollarQuotes(c context.Context, filter quotes.ExportFilter, fn func(quotes.DollarQuote) error) error {
+	for i := range e.quotes {
+		if err := fn(quotes.DollarQuote{Code: "USD", Codein: "BRL", Bid: decimal.NewFromInt(5), Timestamp: int64(i + 1)}); err != nil {
+			return err
+		}
+	}
+	return repository.ErrTimeout
+}
+
+func TestExport(t *testing.T) {
+	repo := repository.NewMemory()
+	for i, bid := range []string{"5.30", "5.35", "5.40"} {
+		quote := quotes.DollarQuote{Code: "USD", Codein: "BRL", Bid: decimal.RequireFromString(bid), Ask: decimal.RequireFromString(bid), Timestamp: 1717171200 + int64(i)*86400}
+		assert.NoError(t, repo.CreateDollarQuote(context.Background(), quote, time.Second))
+	}
+
+	t.Run("should download csv in date range", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/?pair=usd-brl&from=2024-06-01&to=2024-06-02", nil)
+		w := httptest.NewRecorder()
+		NewExport(repo, time.Second).Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="quotes-USD-BRL.csv"`, w.Header().Get("Content-Disposition"))
+		rows, err := csv.NewReader(w.Body).ReadAll()
+		assert.NoError(t, err)
+		assert.Len(t, rows, 2)
+		assert.Equal(t, "5.35", rows[1][8])
+	})
+
+	t.Run("should reject bad parameters", func(t *testing.T) {
+		cases := map[string]string{
+			"/?format=parquet":                export.UnsupportedFormatError,
+			"/?pair=XYZ-BRL":                  quotes.UnsupportedPairError,
+			"/?from=yesterday":                InvalidParameterError,
+			"/?from=2024-06-02&to=2024-06-01": InvalidParameterError,
+		}
+		for url, code := range cases {
+			var response response
+			assert.Equal(t, http.StatusBadRequest, serve(t, NewExport(repo, time.Second).Router, "GET", url, "", &response), url)
+			assert.Equal(t, code, *response.Code, url)
+		}
+	})
+
+	t.Run("should answer an error when nothing was sent", func(t *testing.T) {
+		var response response
+		status := serve(t, NewExport(brokenExporter{}, time.Second).Router, "GET", "/?format=json", "", &response)
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+		assert.Equal(t, repository.TimeoutError, *response.Code)
+	})
+
+	t.Run("should abort a download failing midway", func(t *testing.T) {
+		// enough rows to get past the response buffer
+		server := httptest.NewServer(NewExport(brokenExporter{quotes: 1000}, time.Second).Router)
+		defer server.Close()
+
+		res, err := http.Get(server.URL + "/?format=ndjson")
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		_, err = io.ReadAll(res.Body)
+		assert.True(t, errors.Is(err, io.ErrUnexpectedEOF), "got %v", err)
+	})
+}
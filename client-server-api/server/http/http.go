@@ -1,45 +1,325 @@
 package httpserver
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/alerts"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/export"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/repository"
 	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/usecase"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
 )
 
+const (
+	InvalidParameterError = "INVALID_PARAMETER"
+	InternalError         = "INTERNAL_ERROR"
+	ClientClosedError     = "CLIENT_CLOSED_REQUEST"
+
+	// statusClientClosed is the nginx convention for a client that went away before the
+	// response; it only shows up in logs and metrics.
+	statusClientClosed = 499
+)
+
+var errInvalidParameter = errors.New(InvalidParameterError)
+
 type (
 	handler struct {
-		usecase usecase.GetterDollarQuote
+		usecase usecase.QuoteUsecase
 		Router  chi.Router
 	}
 
+	// Err is the error message and Code its machine readable code, see errorStatus.
 	response struct {
-		Err   *string
-		Value *string
+		Err       *string
+		Code      *string
+		Value     *string
+		Cached    bool
+		Stale     bool
+		AgeMs     int64
+		Timestamp int64
+	}
+
+	historyResponse struct {
+		Err    *string
+		Code   *string
+		Quotes []quotes.DollarQuote
+		Limit  int
+		Offset int
+	}
+
+	candlesResponse struct {
+		Err     *string
+		Code    *string
+		Candles []quotes.Candle
+	}
+
+	// convertResponse carries the amounts as decimal strings, see usecase.Conversion.
+	convertResponse struct {
+		Err       *string
+		Code      *string
+		From      string
+		To        string
+		Side      string
+		Amount    *decimal.Decimal
+		Rate      *decimal.Decimal
+		Result    *decimal.Decimal
+		Pairs     []string
+		Cached    bool
+		Stale     bool
+		Timestamp int64
 	}
 )
 
-func New(u usecase.GetterDollarQuote) *handler {
+func New(u usecase.QuoteUsecase) *handler {
 	r := chi.NewRouter()
 	handler := &handler{
 		usecase: u,
 		Router:  r,
 	}
 	r.Get("/cotacao", handler.getDollarQuote)
+	r.Get("/cotacao/history", handler.getQuoteHistory)
+	r.Get("/cotacao/ohlc", handler.getQuoteCandles)
+	r.Get("/cotacao/{pair}", handler.getDollarQuote)
+	r.Get("/convert", handler.convert)
 	return handler
 }
 
 func (h *handler) getDollarQuote(w http.ResponseWriter, r *http.Request) {
 	response := response{Err: nil}
-	quote, err := h.usecase.GetDollarQuote()
+
+	pair := chi.URLParam(r, "pair")
+	if pair == "" {
+		pair = r.URL.Query().Get("pair")
+	}
+	if pair == "" {
+		pair = quotes.DefaultPair
+	}
+	pair = quotes.NormalizePair(pair)
+
+	if !quotes.IsSupportedPair(pair) {
+		err := fmt.Errorf("%w: %s is not supported, use one of %s", quotes.ErrUnsupportedPair, pair, strings.Join(quotes.SupportedPairs, ", "))
+		response.Err, response.Code = writeError(w, err)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	result, err := h.usecase.GetQuote(r.Context(), pair)
 	if err != nil {
 		log.Println("Error getting quote:", err)
-		errValue := err.Error()
-		response.Err = &errValue
+		response.Err, response.Code = writeError(w, err)
+	} else {
+		bid := result.Quote.Bid.String()
+		response.Value = &bid
+		response.Cached = result.Cached
+		response.Stale = result.Stale
+		response.AgeMs = result.Age.Milliseconds()
+		response.Timestamp = result.Quote.Timestamp
 	}
-	response.Value = quote
 	json.NewEncoder(w).Encode(response)
 }
+
+func (h *handler) getQuoteHistory(w http.ResponseWriter, r *http.Request) {
+	response := historyResponse{}
+	filter, err := parseHistoryFilter(r)
+	if err != nil {
+		response.Err, response.Code = writeError(w, err)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Limit, response.Offset = filter.Limit, filter.Offset
+	history, err := h.usecase.GetQuoteHistory(r.Context(), filter)
+	if err != nil {
+		log.Println("Error getting quote history:", err)
+		response.Err, response.Code = writeError(w, err)
+	}
+	response.Quotes = history
+	json.NewEncoder(w).Encode(response)
+}
+
+func parseHistoryFilter(r *http.Request) (quotes.HistoryFilter, error) {
+	query := r.URL.Query()
+	filter := quotes.HistoryFilter{
+		Pair:  query.Get("pair"),
+		Limit: quotes.DefaultHistoryLimit,
+	}
+
+	if filter.Pair != "" && !quotes.IsSupportedPair(filter.Pair) {
+		return filter, fmt.Errorf("%w: %s", quotes.ErrUnsupportedPair, filter.Pair)
+	}
+
+	var err error
+	if filter.From, err = quotes.ParseTime(query.Get("from")); err != nil {
+		return filter, fmt.Errorf("%w: invalid from: %w", errInvalidParameter, err)
+	}
+	if filter.To, err = quotes.ParseTime(query.Get("to")); err != nil {
+		return filter, fmt.Errorf("%w: invalid to: %w", errInvalidParameter, err)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		return filter, fmt.Errorf("%w: from must not be after to", errInvalidParameter)
+	}
+
+	if v := query.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit <= 0 || filter.Limit > quotes.MaxHistoryLimit {
+			return filter, fmt.Errorf("%w: limit must be between 1 and %d", errInvalidParameter, quotes.MaxHistoryLimit)
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil || filter.Offset < 0 {
+			return filter, fmt.Errorf("%w: offset must be a non negative integer", errInvalidParameter)
+		}
+	}
+
+	return filter, nil
+}
+
+func (h *handler) getQuoteCandles(w http.ResponseWriter, r *http.Request) {
+	response := candlesResponse{}
+	filter, err := parseCandleFilter(r)
+	if err != nil {
+		response.Err, response.Code = writeError(w, err)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	candles, err := h.usecase.GetQuoteCandles(r.Context(), filter)
+	if err != nil {
+		log.Println("Error getting quote candles:", err)
+		response.Err, response.Code = writeError(w, err)
+	}
+	response.Candles = candles
+	json.NewEncoder(w).Encode(response)
+}
+
+func parseCandleFilter(r *http.Request) (quotes.CandleFilter, error) {
+	query := r.URL.Query()
+	filter := quotes.CandleFilter{
+		Pair:     quotes.NormalizePair(query.Get("pair")),
+		Interval: query.Get("interval"),
+		Limit:    quotes.DefaultHistoryLimit,
+	}
+	if filter.Pair == "" {
+		filter.Pair = quotes.DefaultPair
+	}
+	if filter.Interval == "" {
+		filter.Interval = quotes.DefaultCandleInterval
+	}
+
+	if !quotes.IsSupportedPair(filter.Pair) {
+		return filter, fmt.Errorf("%w: %s", quotes.ErrUnsupportedPair, filter.Pair)
+	}
+	if _, ok := quotes.CandleIntervals[filter.Interval]; !ok {
+		return filter, fmt.Errorf("%w: %s", quotes.ErrUnsupportedInterval, filter.Interval)
+	}
+
+	var err error
+	if filter.From, err = quotes.ParseTime(query.Get("from")); err != nil {
+		return filter, fmt.Errorf("%w: invalid from: %w", errInvalidParameter, err)
+	}
+	if filter.To, err = quotes.ParseTime(query.Get("to")); err != nil {
+		return filter, fmt.Errorf("%w: invalid to: %w", errInvalidParameter, err)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		return filter, fmt.Errorf("%w: from must not be after to", errInvalidParameter)
+	}
+
+	if v := query.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit <= 0 || filter.Limit > quotes.MaxHistoryLimit {
+			return filter, fmt.Errorf("%w: limit must be between 1 and %d", errInvalidParameter, quotes.MaxHistoryLimit)
+		}
+	}
+
+	return filter, nil
+}
+
+func (h *handler) convert(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	response := convertResponse{
+		From: quotes.NormalizeCurrency(query.Get("from")),
+		To:   quotes.NormalizeCurrency(query.Get("to")),
+		Side: query.Get("side"),
+	}
+
+	amount, err := decimal.NewFromString(query.Get("amount"))
+	if err != nil {
+		response.Err, response.Code = writeError(w, fmt.Errorf("%w: amount must be a decimal number", errInvalidParameter))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	conversion, err := h.usecase.Convert(r.Context(), response.From, response.To, amount, response.Side)
+	if err != nil {
+		log.Println("Error converting:", err)
+		response.Err, response.Code = writeError(w, err)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Side = conversion.Side
+	response.Amount, response.Rate, response.Result = &conversion.Amount, &conversion.Rate, &conversion.Result
+	response.Pairs = conversion.Pairs
+	response.Cached = conversion.Cached
+	response.Stale = conversion.Stale
+	response.Timestamp = conversion.Timestamp
+	json.NewEncoder(w).Encode(response)
+}
+
+// errorStatus maps an error to the HTTP status and the code sent to the client:
+// bad input is 400, an upstream timeout 504, a database timeout or a closed stream 503
+// and an upstream failure or malformed payload 502. A client that went away gets a 499
+// and anything else is a 500.
+func errorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, alerts.ErrInvalidRule):
+		return http.StatusBadRequest, alerts.InvalidRuleError
+	case errors.Is(err, alerts.ErrRuleNotFound):
+		return http.StatusNotFound, alerts.RuleNotFoundError
+	case errors.Is(err, quotes.ErrUnsupportedPair):
+		return http.StatusBadRequest, quotes.UnsupportedPairError
+	case errors.Is(err, quotes.ErrUnsupportedInterval):
+		return http.StatusBadRequest, quotes.UnsupportedIntervalError
+	case errors.Is(err, quotes.ErrInvalidConversion):
+		return http.StatusBadRequest, quotes.InvalidConversionError
+	case errors.Is(err, quotes.ErrUnsupportedConversion):
+		return http.StatusBadRequest, quotes.UnsupportedConversionError
+	case errors.Is(err, export.ErrUnsupportedFormat):
+		return http.StatusBadRequest, export.UnsupportedFormatError
+	case errors.Is(err, errInvalidParameter):
+		return http.StatusBadRequest, InvalidParameterError
+	case errors.Is(err, usecase.ErrTimeout):
+		return http.StatusGatewayTimeout, usecase.TimeoutError
+	case errors.Is(err, repository.ErrTimeout):
+		return http.StatusServiceUnavailable, repository.TimeoutError
+	case errors.Is(err, quotes.ErrInvalidQuote):
+		return http.StatusBadGateway, quotes.InvalidQuoteError
+	case errors.Is(err, usecase.ErrUpstream):
+		return http.StatusBadGateway, usecase.UpstreamError
+	case errors.Is(err, repository.ErrNotFound):
+		return http.StatusNotFound, repository.NotFoundError
+	case errors.Is(err, errStreamClosed):
+		return http.StatusServiceUnavailable, StreamClosedError
+	case errors.Is(err, context.Canceled):
+		return statusClientClosed, ClientClosedError
+	default:
+		return http.StatusInternalServerError, InternalError
+	}
+}
+
+// writeError writes the status of err and returns its message and code for the body.
+func writeError(w http.ResponseWriter, err error) (*string, *string) {
+	status, code := errorStatus(err)
+	w.WriteHeader(status)
+
+	message := err.Error()
+	return &message, &code
+}
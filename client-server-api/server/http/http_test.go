@@ -1,29 +1,37 @@
 package httpserver
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/mocks"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/repository"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/usecase"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 )
 
 type mockHolder struct {
 	http.Handler
-	mockS *mocks.MockGetterDollarQuote
+	mockS *mocks.MockQuoteUsecase
 }
 
 func TestGetDollarQuote(t *testing.T) {
 	t.Run("should return dollar quote", func(t *testing.T) {
 		mockHolder, responseWriter := setupTest(t)
-		quote := "5.30"
-		mockHolder.mockS.EXPECT().GetDollarQuote().Return(&quote, nil)
+		quote := "5.3"
+		mockHolder.mockS.EXPECT().GetQuote(gomock.Any(), "USD-BRL").Return(quoteResult("5.30"), nil)
 
 		req, err := http.NewRequest("GET", "/cotacao", nil)
 		if err != nil {
@@ -44,13 +52,314 @@ func TestGetDollarQuote(t *testing.T) {
 		assert.Equal(t, http.StatusOK, responseWriter.Code)
 		assert.Equal(t, expected, actual)
 	})
+
+	t.Run("should return quote for pair in path", func(t *testing.T) {
+		mockHolder, responseWriter := setupTest(t)
+		quote := "6.1"
+		mockHolder.mockS.EXPECT().GetQuote(gomock.Any(), "EUR-BRL").Return(quoteResult("6.10"), nil)
+
+		req, err := http.NewRequest("GET", "/cotacao/eur-brl", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mockHolder.ServeHTTP(responseWriter, req)
+
+		var actual response
+		err = json.NewDecoder(responseWriter.Body).Decode(&actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, http.StatusOK, responseWriter.Code)
+		assert.Equal(t, response{Err: nil, Value: &quote}, actual)
+	})
+
+	t.Run("should return quote for pair in query", func(t *testing.T) {
+		mockHolder, responseWriter := setupTest(t)
+		quote := "350000"
+		mockHolder.mockS.EXPECT().GetQuote(gomock.Any(), "BTC-BRL").Return(quoteResult("350000.00"), nil)
+
+		req, err := http.NewRequest("GET", "/cotacao?pair=BTC-BRL", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mockHolder.ServeHTTP(responseWriter, req)
+
+		var actual response
+		err = json.NewDecoder(responseWriter.Body).Decode(&actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, http.StatusOK, responseWriter.Code)
+		assert.Equal(t, response{Err: nil, Value: &quote}, actual)
+	})
+
+	t.Run("should tell a cached quote apart", func(t *testing.T) {
+		mockHolder, responseWriter := setupTest(t)
+		result := quoteResult("5.31")
+		result.Cached = true
+		result.Age = 1500 * time.Millisecond
+		mockHolder.mockS.EXPECT().GetQuote(gomock.Any(), "USD-BRL").Return(result, nil)
+
+		req, err := http.NewRequest("GET", "/cotacao", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mockHolder.ServeHTTP(responseWriter, req)
+
+		var actual response
+		err = json.NewDecoder(responseWriter.Body).Decode(&actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		quote := "5.31"
+		assert.Equal(t, http.StatusOK, responseWriter.Code)
+		assert.Equal(t, response{Value: &quote, Cached: true, AgeMs: 1500}, actual)
+	})
+
+	t.Run("should flag a stale fallback quote", func(t *testing.T) {
+		mockHolder, responseWriter := setupTest(t)
+		result := quoteResult("5.20")
+		result.Stale = true
+		result.Age = 90 * time.Second
+		result.Quote.Timestamp = 1717171200
+		mockHolder.mockS.EXPECT().GetQuote(gomock.Any(), "USD-BRL").Return(result, nil)
+
+		req, err := http.NewRequest("GET", "/cotacao", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mockHolder.ServeHTTP(responseWriter, req)
+
+		var actual response
+		err = json.NewDecoder(responseWriter.Body).Decode(&actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		quote := "5.2"
+		assert.Equal(t, http.StatusOK, responseWriter.Code)
+		assert.Equal(t, response{Value: &quote, Stale: true, AgeMs: 90000, Timestamp: 1717171200}, actual)
+	})
+
+	t.Run("should reject unsupported pair", func(t *testing.T) {
+		mockHolder, responseWriter := setupTest(t)
+
+		req, err := http.NewRequest("GET", "/cotacao/XYZ-BRL", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mockHolder.ServeHTTP(responseWriter, req)
+
+		var actual response
+		err = json.NewDecoder(responseWriter.Body).Decode(&actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, http.StatusBadRequest, responseWriter.Code)
+		assert.Nil(t, actual.Value)
+		assert.Contains(t, *actual.Err, "UNSUPPORTED_CURRENCY_PAIR")
+		assert.Equal(t, quotes.UnsupportedPairError, *actual.Code)
+	})
+
+	t.Run("should map usecase errors to status and code", func(t *testing.T) {
+		cases := []struct {
+			err    error
+			status int
+			code   string
+		}{
+			{usecase.ErrTimeout, http.StatusGatewayTimeout, usecase.TimeoutError},
+			{repository.ErrTimeout, http.StatusServiceUnavailable, repository.TimeoutError},
+			{fmt.Errorf("%w: upstream returned 500", usecase.ErrUpstream), http.StatusBadGateway, usecase.UpstreamError},
+			{fmt.Errorf("%w: %w: missing timestamp", usecase.ErrUpstream, quotes.ErrInvalidQuote), http.StatusBadGateway, quotes.InvalidQuoteError},
+			{context.Canceled, statusClientClosed, ClientClosedError},
+			{errors.New("boom"), http.StatusInternalServerError, InternalError},
+		}
+		for _, c := range cases {
+			mockHolder, responseWriter := setupTest(t)
+			mockHolder.mockS.EXPECT().GetQuote(gomock.Any(), "USD-BRL").Return(nil, c.err)
+
+			req, err := http.NewRequest("GET", "/cotacao", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mockHolder.ServeHTTP(responseWriter, req)
+
+			var actual response
+			err = json.NewDecoder(responseWriter.Body).Decode(&actual)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			message := c.err.Error()
+			assert.Equal(t, c.status, responseWriter.Code, c.code)
+			assert.Equal(t, response{Err: &message, Code: &c.code}, actual)
+		}
+	})
+}
+
+func TestGetQuoteHistory(t *testing.T) {
+	t.Run("should return quote history", func(t *testing.T) {
+		mockHolder, responseWriter := setupTest(t)
+		history := []quotes.DollarQuote{
+			{Code: "USD", Codein: "BRL", Bid: decimal.RequireFromString("5.30"), Timestamp: 1717171200},
+			{Code: "USD", Codein: "BRL", Bid: decimal.RequireFromString("5.25"), Timestamp: 1717084800},
+		}
+		filter := quotes.HistoryFilter{
+			Pair:   "USD-BRL",
+			From:   time.Unix(1717000000, 0),
+			To:     time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			Limit:  2,
+			Offset: 4,
+		}
+		mockHolder.mockS.EXPECT().GetQuoteHistory(gomock.Any(), filter).Return(history, nil)
+
+		req, err := http.NewRequest("GET", "/cotacao/history?pair=USD-BRL&from=1717000000&to=2024-06-01T00:00:00Z&limit=2&offset=4", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mockHolder.ServeHTTP(responseWriter, req)
+
+		expected, err := json.Marshal(historyResponse{Quotes: history, Limit: 2, Offset: 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, http.StatusOK, responseWriter.Code)
+		assert.JSONEq(t, string(expected), responseWriter.Body.String())
+	})
+
+	t.Run("should reject invalid parameters", func(t *testing.T) {
+		for _, query := range []string{"limit=0", "limit=abc", "offset=-1", "from=yesterday", "from=2024-06-02&to=2024-06-01", "pair=XYZ-BRL"} {
+			mockHolder, responseWriter := setupTest(t)
+
+			req, err := http.NewRequest("GET", "/cotacao/history?"+query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mockHolder.ServeHTTP(responseWriter, req)
+
+			var actual historyResponse
+			err = json.NewDecoder(responseWriter.Body).Decode(&actual)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, http.StatusBadRequest, responseWriter.Code, query)
+			assert.NotNil(t, actual.Err, query)
+		}
+	})
+}
+
+func TestGetQuoteCandles(t *testing.T) {
+	t.Run("should return candles", func(t *testing.T) {
+		mockHolder, responseWriter := setupTest(t)
+		candles := []quotes.Candle{
+			{Pair: "USD-BRL", Interval: "1d", Start: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), Open: decimal.RequireFromString("5.1"), High: decimal.RequireFromString("5.4"), Low: decimal.RequireFromString("5.0"), Close: decimal.RequireFromString("5.2"), AvgSpread: decimal.RequireFromString("0.01"), Count: 3},
+		}
+		filter := quotes.CandleFilter{Pair: "USD-BRL", Interval: "1d", Limit: quotes.DefaultHistoryLimit}
+		mockHolder.mockS.EXPECT().GetQuoteCandles(gomock.Any(), filter).Return(candles, nil)
+
+		req, err := http.NewRequest("GET", "/cotacao/ohlc?interval=1d", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mockHolder.ServeHTTP(responseWriter, req)
+
+		expected, err := json.Marshal(candlesResponse{Candles: candles})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, http.StatusOK, responseWriter.Code)
+		assert.JSONEq(t, string(expected), responseWriter.Body.String())
+	})
+
+	t.Run("should reject unsupported interval", func(t *testing.T) {
+		mockHolder, responseWriter := setupTest(t)
+
+		req, err := http.NewRequest("GET", "/cotacao/ohlc?interval=7s", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mockHolder.ServeHTTP(responseWriter, req)
+
+		var actual candlesResponse
+		err = json.NewDecoder(responseWriter.Body).Decode(&actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, http.StatusBadRequest, responseWriter.Code)
+		assert.Contains(t, *actual.Err, quotes.UnsupportedIntervalError)
+		assert.Equal(t, quotes.UnsupportedIntervalError, *actual.Code)
+	})
+}
+
+func TestConvert(t *testing.T) {
+	t.Run("should convert amount", func(t *testing.T) {
+		mockHolder, _ := setupTest(t)
+		amount := decimal.RequireFromString("123.45")
+		mockHolder.mockS.EXPECT().Convert(gomock.Any(), "USD", "BRL", amount, "ask").Return(&usecase.Conversion{
+			From: "USD", To: "BRL", Side: "ask", Amount: amount,
+			Rate: decimal.RequireFromString("5.5"), Result: decimal.RequireFromString("678.975"),
+			Pairs: []string{"USD-BRL"}, Timestamp: 1717171200,
+		}, nil)
+
+		var actual map[string]any
+		status := serve(t, mockHolder, "GET", "/convert?from=usd&to=BRL&amount=123.45&side=ask", "", &actual)
+
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "678.975", actual["Result"])
+		assert.Equal(t, "5.5", actual["Rate"])
+		assert.Equal(t, []any{"USD-BRL"}, actual["Pairs"])
+	})
+
+	t.Run("should reject bad amount", func(t *testing.T) {
+		mockHolder, _ := setupTest(t)
+
+		var actual convertResponse
+		status := serve(t, mockHolder, "GET", "/convert?from=USD&to=BRL&amount=1e", "", &actual)
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, InvalidParameterError, *actual.Code)
+	})
+
+	t.Run("should map unsupported conversion", func(t *testing.T) {
+		mockHolder, _ := setupTest(t)
+		mockHolder.mockS.EXPECT().Convert(gomock.Any(), "XYZ", "BRL", gomock.Any(), "").Return(nil, fmt.Errorf("%w: XYZ", quotes.ErrUnsupportedConversion))
+
+		var actual convertResponse
+		status := serve(t, mockHolder, "GET", "/convert?from=XYZ&to=BRL&amount=1", "", &actual)
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, quotes.UnsupportedConversionError, *actual.Code)
+		assert.Nil(t, actual.Result)
+	})
+}
+
+func quoteResult(bid string) *usecase.QuoteResult {
+	return &usecase.QuoteResult{Quote: quotes.DollarQuote{Bid: decimal.RequireFromString(bid)}}
 }
 
 func setupTest(t *testing.T) (*mockHolder, *httptest.ResponseRecorder) {
 	ctrl := gomock.NewController(t)
 	responseWriter := httptest.NewRecorder()
 
-	mockS := mocks.NewMockGetterDollarQuote(ctrl)
+	mockS := mocks.NewMockQuoteUsecase(ctrl)
 
 	h := New(mockS)
 	r := chi.NewRouter()
@@ -0,0 +1,41 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/scheduler"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type (
+	StatusReporter interface {
+		Status() []scheduler.JobStatus
+	}
+
+	schedulerHandler struct {
+		scheduler StatusReporter
+		Router    chi.Router
+	}
+
+	schedulerResponse struct {
+		Err  *string
+		Code *string
+		Jobs []scheduler.JobStatus
+	}
+)
+
+func NewScheduler(s StatusReporter) *schedulerHandler {
+	r := chi.NewRouter()
+	handler := &schedulerHandler{
+		scheduler: s,
+		Router:    r,
+	}
+	r.Get("/", handler.getStatus)
+	return handler
+}
+
+func (h *schedulerHandler) getStatus(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(schedulerResponse{Jobs: h.scheduler.Status()})
+}
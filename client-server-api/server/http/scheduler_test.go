@@ -0,0 +1,35 @@
+package httpserver
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/scheduler"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeScheduler []scheduler.JobStatus
+
+func (s fakeScheduler) Status() []scheduler.JobStatus {
+	return s
+}
+
+func TestSchedulerStatus(t *testing.T) {
+	t.Run("should list jobs", func(t *testing.T) {
+		next := time.Unix(1717171230, 0).UTC()
+		handler := NewScheduler(fakeScheduler{
+			{Pair: "USD-BRL", Schedule: "every 30s", Runs: 2, Failures: 1, LastError: "EXTERNAL_API_CALL_TIMEOUT", NextRun: &next},
+		}).Router
+
+		var response schedulerResponse
+		assert.Equal(t, http.StatusOK, serve(t, handler, "GET", "/", "", &response))
+		assert.Nil(t, response.Err)
+		assert.Len(t, response.Jobs, 1)
+		assert.Equal(t, "USD-BRL", response.Jobs[0].Pair)
+		assert.Equal(t, "EXTERNAL_API_CALL_TIMEOUT", response.Jobs[0].LastError)
+		assert.Equal(t, next, *response.Jobs[0].NextRun)
+		assert.Nil(t, response.Jobs[0].LastRun)
+	})
+}
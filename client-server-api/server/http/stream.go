@@ -0,0 +1,134 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/stream"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const StreamClosedError = "STREAM_CLOSED"
+
+var errStreamClosed = errors.New(StreamClosedError)
+
+type (
+	Subscriber interface {
+		Subscribe(pairs []string) *stream.Subscription
+	}
+
+	// streamHandler serves quotes as Server-Sent Events: a "quote" event per new quote,
+	// a comment every heartbeat, and a "dropped" event before closing the stream of a
+	// subscriber that fell behind. A write taking longer than heartbeat ends the stream.
+	streamHandler struct {
+		hub       Subscriber
+		heartbeat time.Duration
+		Router    chi.Router
+	}
+)
+
+func NewStream(hub Subscriber, heartbeat time.Duration) *streamHandler {
+	if heartbeat <= 0 {
+		heartbeat = 15 * time.Second
+	}
+
+	r := chi.NewRouter()
+	handler := &streamHandler{
+		hub:       hub,
+		heartbeat: heartbeat,
+		Router:    r,
+	}
+	r.Get("/", handler.streamQuotes)
+	return handler
+}
+
+func (h *streamHandler) streamQuotes(w http.ResponseWriter, r *http.Request) {
+	pairs, err := parsePairs(r)
+	var sub *stream.Subscription
+	if err == nil {
+		if sub = h.hub.Subscribe(pairs); sub == nil {
+			err = errStreamClosed
+		}
+	}
+	if err != nil {
+		response := response{}
+		response.Err, response.Code = writeError(w, err)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+	defer sub.Cancel()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		log.Println("Error starting quote stream:", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var event string
+		select {
+		case <-r.Context().Done():
+			return
+		case quote, ok := <-sub.Quotes:
+			if !ok {
+				if sub.Dropped() {
+					h.write(w, rc, "event: dropped\ndata: {}\n\n")
+				}
+				return
+			}
+			data, err := json.Marshal(quote)
+			if err != nil {
+				log.Println("Error encoding streamed quote:", err)
+				continue
+			}
+			event = fmt.Sprintf("id: %d\nevent: quote\ndata: %s\n\n", quote.Timestamp, data)
+		case <-heartbeat.C:
+			event = ": heartbeat\n\n"
+		}
+
+		if err := h.write(w, rc, event); err != nil {
+			return
+		}
+	}
+}
+
+func (h *streamHandler) write(w http.ResponseWriter, rc *http.ResponseController, event string) error {
+	rc.SetWriteDeadline(time.Now().Add(h.heartbeat))
+	if _, err := fmt.Fprint(w, event); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
+
+// parsePairs reads the pairs of the pair parameter, repeated or comma separated; none
+// means every pair.
+func parsePairs(r *http.Request) ([]string, error) {
+	var pairs []string
+	for _, value := range r.URL.Query()["pair"] {
+		for _, pair := range strings.Split(value, ",") {
+			if pair = quotes.NormalizePair(pair); pair == "" {
+				continue
+			}
+			if !quotes.IsSupportedPair(pair) {
+				return nil, fmt.Errorf("%w: %s is not supported, use one of %s", quotes.ErrUnsupportedPair, pair, strings.Join(quotes.SupportedPairs, ", "))
+			}
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs, nil
+}
@@ -0,0 +1,160 @@
+package httpserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/stream"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+type sseEvent struct {
+	comment string
+	event   string
+	id      string
+	data    string
+}
+
+// readEvents parses the Server-Sent Events of body onto a channel, closed at EOF.
+func readEvents(body *bufio.Reader) chan sseEvent {
+	events := make(chan sseEvent, 16)
+	go func() {
+		defer close(events)
+		var event sseEvent
+		for {
+			line, err := body.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "":
+				events <- event
+				event = sseEvent{}
+			case strings.HasPrefix(line, ":"):
+				event.comment = strings.TrimSpace(line[1:])
+			case strings.HasPrefix(line, "event: "):
+				event.event = line[len("event: "):]
+			case strings.HasPrefix(line, "id: "):
+				event.id = line[len("id: "):]
+			case strings.HasPrefix(line, "data: "):
+				event.data = line[len("data: "):]
+			}
+		}
+	}()
+	return events
+}
+
+func next(t *testing.T, events chan sseEvent) sseEvent {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+		return sseEvent{}
+	}
+}
+
+// openStream GETs url and parses its events. The body is closed at cleanup, before a
+// server registered earlier is closed, which would otherwise wait for the stream.
+func openStream(t *testing.T, url string) (*http.Response, chan sseEvent) {
+	res, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	return res, readEvents(bufio.NewReader(res.Body))
+}
+
+func TestStream(t *testing.T) {
+	usd := quotes.DollarQuote{Code: "USD", Codein: "BRL", Bid: decimal.RequireFromString("5.35"), Ask: decimal.RequireFromString("5.36"), Timestamp: 1717171200}
+	eur := quotes.DollarQuote{Code: "EUR", Codein: "BRL", Bid: decimal.RequireFromString("5.80"), Ask: decimal.RequireFromString("5.81"), Timestamp: 1717171200}
+
+	t.Run("should push quotes of subscribed pairs and heartbeats", func(t *testing.T) {
+		hub := stream.NewHub(4)
+		hub.Publish(usd)
+		server := httptest.NewServer(NewStream(hub, 50*time.Millisecond).Router)
+		t.Cleanup(server.Close)
+
+		res, events := openStream(t, server.URL+"/?pair=usd-brl")
+		assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+		event := next(t, events)
+		assert.Equal(t, "quote", event.event)
+		assert.Equal(t, "1717171200", event.id)
+		var quote quotes.DollarQuote
+		assert.NoError(t, json.Unmarshal([]byte(event.data), &quote))
+		assert.Equal(t, "5.35", quote.Bid.String())
+
+		hub.Publish(eur)
+		usd.Timestamp++
+		hub.Publish(usd)
+		event = next(t, events)
+		assert.Equal(t, "1717171201", event.id, "EUR-BRL was not subscribed")
+
+		assert.Equal(t, "heartbeat", next(t, events).comment)
+	})
+
+	t.Run("should tell a dropped subscriber", func(t *testing.T) {
+		hub := stream.NewHub(1)
+		server := httptest.NewServer(NewStream(hub, time.Minute).Router)
+		defer server.Close()
+
+		res, err := http.Get(server.URL + "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		assert.Eventually(t, func() bool { return hub.Stats().Subscribers == 1 }, time.Second, time.Millisecond)
+
+		// nobody reads the body, so the handler falls behind once the socket buffers fill
+		for i := int64(0); hub.Stats().Dropped == 0; i++ {
+			if i == 1_000_000 {
+				t.Fatal("subscriber never dropped")
+			}
+			hub.Publish(quotes.DollarQuote{Code: "USD", Codein: "BRL", Bid: decimal.NewFromInt(i), Timestamp: i})
+		}
+
+		var dropped bool
+		for event := range readEvents(bufio.NewReader(res.Body)) {
+			dropped = dropped || event.event == "dropped"
+		}
+		assert.True(t, dropped)
+	})
+
+	t.Run("should end streams when the hub closes", func(t *testing.T) {
+		hub := stream.NewHub(4)
+		server := httptest.NewServer(NewStream(hub, time.Minute).Router)
+		t.Cleanup(server.Close)
+
+		_, events := openStream(t, server.URL+"/")
+		assert.Eventually(t, func() bool { return hub.Stats().Subscribers == 1 }, time.Second, time.Millisecond)
+		hub.Close()
+
+		select {
+		case _, ok := <-events:
+			assert.False(t, ok)
+		case <-time.After(time.Second):
+			t.Fatal("stream still open")
+		}
+
+		var response response
+		assert.Equal(t, http.StatusServiceUnavailable, serve(t, NewStream(hub, time.Minute).Router, "GET", "/", "", &response))
+		assert.Equal(t, StreamClosedError, *response.Code)
+	})
+
+	t.Run("should reject unsupported pair", func(t *testing.T) {
+		var response response
+		status := serve(t, NewStream(stream.NewHub(1), time.Minute).Router, "GET", "/?pair=USD-BRL,XYZ-BRL", "", &response)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, quotes.UnsupportedPairError, *response.Code)
+	})
+}
@@ -10,8 +10,12 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
+	quotes "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+	usecase "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/usecase"
+	decimal "github.com/shopspring/decimal"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -40,16 +44,247 @@ func (m *MockGetterDollarQuote) EXPECT() *MockGetterDollarQuoteMockRecorder {
 }
 
 // GetDollarQuote mocks base method.
-func (m *MockGetterDollarQuote) GetDollarQuote() (*string, error) {
+func (m *MockGetterDollarQuote) GetDollarQuote(ctx context.Context) (*string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetDollarQuote")
+	ret := m.ctrl.Call(m, "GetDollarQuote", ctx)
 	ret0, _ := ret[0].(*string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetDollarQuote indicates an expected call of GetDollarQuote.
-func (mr *MockGetterDollarQuoteMockRecorder) GetDollarQuote() *gomock.Call {
+func (mr *MockGetterDollarQuoteMockRecorder) GetDollarQuote(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDollarQuote", reflect.TypeOf((*MockGetterDollarQuote)(nil).GetDollarQuote))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDollarQuote", reflect.TypeOf((*MockGetterDollarQuote)(nil).GetDollarQuote), ctx)
+}
+
+// GetQuote mocks base method.
+func (m *MockGetterDollarQuote) GetQuote(ctx context.Context, pair string) (*usecase.QuoteResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQuote", ctx, pair)
+	ret0, _ := ret[0].(*usecase.QuoteResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQuote indicates an expected call of GetQuote.
+func (mr *MockGetterDollarQuoteMockRecorder) GetQuote(ctx, pair any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQuote", reflect.TypeOf((*MockGetterDollarQuote)(nil).GetQuote), ctx, pair)
+}
+
+// MockGetterQuoteHistory is a mock of GetterQuoteHistory interface.
+type MockGetterQuoteHistory struct {
+	ctrl     *gomock.Controller
+	recorder *MockGetterQuoteHistoryMockRecorder
+	isgomock struct{}
+}
+
+// MockGetterQuoteHistoryMockRecorder is the mock recorder for MockGetterQuoteHistory.
+type MockGetterQuoteHistoryMockRecorder struct {
+	mock *MockGetterQuoteHistory
+}
+
+// NewMockGetterQuoteHistory creates a new mock instance.
+func NewMockGetterQuoteHistory(ctrl *gomock.Controller) *MockGetterQuoteHistory {
+	mock := &MockGetterQuoteHistory{ctrl: ctrl}
+	mock.recorder = &MockGetterQuoteHistoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGetterQuoteHistory) EXPECT() *MockGetterQuoteHistoryMockRecorder {
+	return m.recorder
+}
+
+// GetQuoteHistory mocks base method.
+func (m *MockGetterQuoteHistory) GetQuoteHistory(ctx context.Context, filter quotes.HistoryFilter) ([]quotes.DollarQuote, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQuoteHistory", ctx, filter)
+	ret0, _ := ret[0].([]quotes.DollarQuote)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQuoteHistory indicates an expected call of GetQuoteHistory.
+func (mr *MockGetterQuoteHistoryMockRecorder) GetQuoteHistory(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQuoteHistory", reflect.TypeOf((*MockGetterQuoteHistory)(nil).GetQuoteHistory), ctx, filter)
+}
+
+// MockGetterQuoteCandles is a mock of GetterQuoteCandles interface.
+type MockGetterQuoteCandles struct {
+	ctrl     *gomock.Controller
+	recorder *MockGetterQuoteCandlesMockRecorder
+	isgomock struct{}
+}
+
+// MockGetterQuoteCandlesMockRecorder is the mock recorder for MockGetterQuoteCandles.
+type MockGetterQuoteCandlesMockRecorder struct {
+	mock *MockGetterQuoteCandles
+}
+
+// NewMockGetterQuoteCandles creates a new mock instance.
+func NewMockGetterQuoteCandles(ctrl *gomock.Controller) *MockGetterQuoteCandles {
+	mock := &MockGetterQuoteCandles{ctrl: ctrl}
+	mock.recorder = &MockGetterQuoteCandlesMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGetterQuoteCandles) EXPECT() *MockGetterQuoteCandlesMockRecorder {
+	return m.recorder
+}
+
+// GetQuoteCandles mocks base method.
+func (m *MockGetterQuoteCandles) GetQuoteCandles(ctx context.Context, filter quotes.CandleFilter) ([]quotes.Candle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQuoteCandles", ctx, filter)
+	ret0, _ := ret[0].([]quotes.Candle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQuoteCandles indicates an expected call of GetQuoteCandles.
+func (mr *MockGetterQuoteCandlesMockRecorder) GetQuoteCandles(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQuoteCandles", reflect.TypeOf((*MockGetterQuoteCandles)(nil).GetQuoteCandles), ctx, filter)
+}
+
+// MockConverterQuote is a mock of ConverterQuote interface.
+type MockConverterQuote struct {
+	ctrl     *gomock.Controller
+	recorder *MockConverterQuoteMockRecorder
+	isgomock struct{}
+}
+
+// MockConverterQuoteMockRecorder is the mock recorder for MockConverterQuote.
+type MockConverterQuoteMockRecorder struct {
+	mock *MockConverterQuote
+}
+
+// NewMockConverterQuote creates a new mock instance.
+func NewMockConverterQuote(ctrl *gomock.Controller) *MockConverterQuote {
+	mock := &MockConverterQuote{ctrl: ctrl}
+	mock.recorder = &MockConverterQuoteMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockConverterQuote) EXPECT() *MockConverterQuoteMockRecorder {
+	return m.recorder
+}
+
+// Convert mocks base method.
+func (m *MockConverterQuote) Convert(ctx context.Context, from, to string, amount decimal.Decimal, side string) (*usecase.Conversion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Convert", ctx, from, to, amount, side)
+	ret0, _ := ret[0].(*usecase.Conversion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Convert indicates an expected call of Convert.
+func (mr *MockConverterQuoteMockRecorder) Convert(ctx, from, to, amount, side any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Convert", reflect.TypeOf((*MockConverterQuote)(nil).Convert), ctx, from, to, amount, side)
+}
+
+// MockQuoteUsecase is a mock of QuoteUsecase interface.
+type MockQuoteUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockQuoteUsecaseMockRecorder
+	isgomock struct{}
+}
+
+// MockQuoteUsecaseMockRecorder is the mock recorder for MockQuoteUsecase.
+type MockQuoteUsecaseMockRecorder struct {
+	mock *MockQuoteUsecase
+}
+
+// NewMockQuoteUsecase creates a new mock instance.
+func NewMockQuoteUsecase(ctrl *gomock.Controller) *MockQuoteUsecase {
+	mock := &MockQuoteUsecase{ctrl: ctrl}
+	mock.recorder = &MockQuoteUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockQuoteUsecase) EXPECT() *MockQuoteUsecaseMockRecorder {
+	return m.recorder
+}
+
+// Convert mocks base method.
+func (m *MockQuoteUsecase) Convert(ctx context.Context, from, to string, amount decimal.Decimal, side string) (*usecase.Conversion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Convert", ctx, from, to, amount, side)
+	ret0, _ := ret[0].(*usecase.Conversion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Convert indicates an expected call of Convert.
+func (mr *MockQuoteUsecaseMockRecorder) Convert(ctx, from, to, amount, side any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Convert", reflect.TypeOf((*MockQuoteUsecase)(nil).Convert), ctx, from, to, amount, side)
+}
+
+// GetDollarQuote mocks base method.
+func (m *MockQuoteUsecase) GetDollarQuote(ctx context.Context) (*string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDollarQuote", ctx)
+	ret0, _ := ret[0].(*string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDollarQuote indicates an expected call of GetDollarQuote.
+func (mr *MockQuoteUsecaseMockRecorder) GetDollarQuote(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDollarQuote", reflect.TypeOf((*MockQuoteUsecase)(nil).GetDollarQuote), ctx)
+}
+
+// GetQuote mocks base method.
+func (m *MockQuoteUsecase) GetQuote(ctx context.Context, pair string) (*usecase.QuoteResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQuote", ctx, pair)
+	ret0, _ := ret[0].(*usecase.QuoteResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQuote indicates an expected call of GetQuote.
+func (mr *MockQuoteUsecaseMockRecorder) GetQuote(ctx, pair any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQuote", reflect.TypeOf((*MockQuoteUsecase)(nil).GetQuote), ctx, pair)
+}
+
+// GetQuoteCandles mocks base method.
+func (m *MockQuoteUsecase) GetQuoteCandles(ctx context.Context, filter quotes.CandleFilter) ([]quotes.Candle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQuoteCandles", ctx, filter)
+	ret0, _ := ret[0].([]quotes.Candle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQuoteCandles indicates an expected call of GetQuoteCandles.
+func (mr *MockQuoteUsecaseMockRecorder) GetQuoteCandles(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQuoteCandles", reflect.TypeOf((*MockQuoteUsecase)(nil).GetQuoteCandles), ctx, filter)
+}
+
+// GetQuoteHistory mocks base method.
+func (m *MockQuoteUsecase) GetQuoteHistory(ctx context.Context, filter quotes.HistoryFilter) ([]quotes.DollarQuote, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQuoteHistory", ctx, filter)
+	ret0, _ := ret[0].([]quotes.DollarQuote)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQuoteHistory indicates an expected call of GetQuoteHistory.
+func (mr *MockQuoteUsecaseMockRecorder) GetQuoteHistory(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQuoteHistory", reflect.TypeOf((*MockQuoteUsecase)(nil).GetQuoteHistory), ctx, filter)
 }
@@ -0,0 +1,90 @@
+package quotes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	SideBid = "bid"
+	SideAsk = "ask"
+
+	// PivotCurrency is the currency cross rates are computed through.
+	PivotCurrency = "BRL"
+
+	// ConversionPrecision is the number of decimal places of a converted amount and
+	// RatePrecision those of its rate.
+	ConversionPrecision = 8
+	RatePrecision       = 16
+
+	InvalidConversionError     = "INVALID_CONVERSION"
+	UnsupportedConversionError = "UNSUPPORTED_CONVERSION"
+)
+
+var (
+	ErrInvalidConversion     = errors.New(InvalidConversionError)
+	ErrUnsupportedConversion = errors.New(UnsupportedConversionError)
+)
+
+// ConversionLeg is one quote of a conversion path: the amount is multiplied by the
+// price of Pair, or divided by it when Inverse.
+type ConversionLeg struct {
+	Pair    string
+	Inverse bool
+}
+
+// ConversionPath returns the quotes converting from into to: the supported pair, its
+// inverse or, when there is neither, one leg into PivotCurrency and one out of it. The
+// path is empty when both are the same currency.
+func ConversionPath(from, to string) ([]ConversionLeg, error) {
+	from, to = NormalizeCurrency(from), NormalizeCurrency(to)
+	if from == "" || to == "" {
+		return nil, fmt.Errorf("%w: from and to are required", ErrInvalidConversion)
+	}
+	if from == to {
+		return nil, nil
+	}
+
+	if leg, ok := conversionLeg(from, to); ok {
+		return []ConversionLeg{leg}, nil
+	}
+	if from != PivotCurrency && to != PivotCurrency {
+		first, okFirst := conversionLeg(from, PivotCurrency)
+		second, okSecond := conversionLeg(PivotCurrency, to)
+		if okFirst && okSecond {
+			return []ConversionLeg{first, second}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no supported pair converts %s into %s", ErrUnsupportedConversion, from, to)
+}
+
+func conversionLeg(from, to string) (ConversionLeg, bool) {
+	if pair := from + "-" + to; IsSupportedPair(pair) {
+		return ConversionLeg{Pair: pair}, true
+	}
+	if pair := to + "-" + from; IsSupportedPair(pair) {
+		return ConversionLeg{Pair: pair, Inverse: true}, true
+	}
+	return ConversionLeg{}, false
+}
+
+// NormalizeCurrency uppercases and trims a currency code.
+func NormalizeCurrency(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Price returns the bid or the ask of the quote; side is SideBid or SideAsk.
+func (d DollarQuote) Price(side string) (decimal.Decimal, error) {
+	switch side {
+	case SideBid:
+		return d.Bid, nil
+	case SideAsk:
+		return d.Ask, nil
+	default:
+		return decimal.Decimal{}, fmt.Errorf("%w: side must be %s or %s, got %q", ErrInvalidConversion, SideBid, SideAsk, side)
+	}
+}
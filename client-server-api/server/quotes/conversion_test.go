@@ -0,0 +1,32 @@
+package quotes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConversionPath(t *testing.T) {
+	t.Run("should find direct, inverse and cross paths", func(t *testing.T) {
+		cases := map[[2]string][]ConversionLeg{
+			{"usd", "brl "}: {{Pair: "USD-BRL"}},
+			{"BRL", "EUR"}:  {{Pair: "EUR-BRL", Inverse: true}},
+			{"EUR", "USD"}:  {{Pair: "EUR-USD"}},
+			{"GBP", "JPY"}:  {{Pair: "GBP-BRL"}, {Pair: "JPY-BRL", Inverse: true}},
+			{"BRL", "BRL"}:  nil,
+		}
+		for currencies, want := range cases {
+			path, err := ConversionPath(currencies[0], currencies[1])
+			assert.NoError(t, err, currencies)
+			assert.Equal(t, want, path, currencies)
+		}
+	})
+
+	t.Run("should reject unknown and missing currencies", func(t *testing.T) {
+		_, err := ConversionPath("XYZ", "BRL")
+		assert.ErrorIs(t, err, ErrUnsupportedConversion)
+
+		_, err = ConversionPath("", "BRL")
+		assert.ErrorIs(t, err, ErrInvalidConversion)
+	})
+}
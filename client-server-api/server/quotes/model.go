@@ -1,15 +1,186 @@
 package quotes
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	DefaultPair          = "USD-BRL"
+	UnsupportedPairError = "UNSUPPORTED_CURRENCY_PAIR"
+
+	DefaultHistoryLimit = 100
+	MaxHistoryLimit     = 1000
+
+	DefaultCandleInterval    = "1h"
+	UnsupportedIntervalError = "UNSUPPORTED_CANDLE_INTERVAL"
+
+	InvalidQuoteError = "INVALID_QUOTE_PAYLOAD"
+)
+
+// Sentinel errors, matched with errors.Is; their message is the error code.
+var (
+	ErrUnsupportedPair     = errors.New(UnsupportedPairError)
+	ErrUnsupportedInterval = errors.New(UnsupportedIntervalError)
+	ErrInvalidQuote        = errors.New(InvalidQuoteError)
+)
+
+var CandleIntervals = map[string]time.Duration{
+	"1m":  time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"1h":  time.Hour,
+	"4h":  4 * time.Hour,
+	"1d":  24 * time.Hour,
+}
+
+var SupportedPairs = []string{
+	"USD-BRL",
+	"EUR-BRL",
+	"GBP-BRL",
+	"JPY-BRL",
+	"CAD-BRL",
+	"ARS-BRL",
+	"BTC-BRL",
+	"ETH-BRL",
+	"USD-EUR",
+	"EUR-USD",
+}
+
+// DollarQuote mirrors the awesomeapi payload. Prices are decimals and Timestamp is unix
+// seconds; both keep the upstream string encoding in JSON. Reference marks a daily
+// reference rate, which has no spread and the timestamp of its day: it can be served but
+// is never stored, so it does not skew history and candles.
 type DollarQuote struct {
-	Code       string `json:"code"`
-	Codein     string `json:"codein"`
-	Name       string `json:"name"`
-	High       string `json:"high"`
-	Low        string `json:"low"`
-	VarBid     string `json:"varBid"`
-	PctChange  string `json:"pctChange"`
-	Bid        string `json:"bid"`
-	Ask        string `json:"ask"`
-	Timestamp  string `json:"timestamp"`
-	CreateDate string `json:"create_date"`
+	Code       string          `json:"code"`
+	Codein     string          `json:"codein"`
+	Name       string          `json:"name"`
+	High       decimal.Decimal `json:"high"`
+	Low        decimal.Decimal `json:"low"`
+	VarBid     decimal.Decimal `json:"varBid"`
+	PctChange  decimal.Decimal `json:"pctChange"`
+	Bid        decimal.Decimal `json:"bid"`
+	Ask        decimal.Decimal `json:"ask"`
+	Timestamp  int64           `json:"timestamp,string"`
+	CreateDate string          `json:"create_date"`
+	Reference  bool            `json:"-"`
+}
+
+// HistoryFilter selects stored quotes. Zero From/To leave the range open on that side,
+// an empty Pair matches every pair.
+type HistoryFilter struct {
+	Pair   string
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+// ExportFilter selects the stored quotes to export, see HistoryFilter; there is no limit.
+type ExportFilter struct {
+	Pair string
+	From time.Time
+	To   time.Time
+}
+
+// CandleFilter selects the quotes aggregated into candles. Interval is one of the
+// CandleIntervals keys; buckets are aligned to the unix epoch (UTC).
+type CandleFilter struct {
+	Pair     string
+	Interval string
+	From     time.Time
+	To       time.Time
+	Limit    int
+}
+
+// Candle is the open/high/low/close of the bid price within one bucket, plus the
+// average ask-bid spread of the quotes in it.
+type Candle struct {
+	Pair      string          `json:"pair"`
+	Interval  string          `json:"interval"`
+	Start     time.Time       `json:"start"`
+	Open      decimal.Decimal `json:"open"`
+	High      decimal.Decimal `json:"high"`
+	Low       decimal.Decimal `json:"low"`
+	Close     decimal.Decimal `json:"close"`
+	AvgSpread decimal.Decimal `json:"avgSpread"`
+	Count     int             `json:"count"`
+}
+
+// ParseDollarQuotes decodes the upstream array payload and validates every quote in it.
+func ParseDollarQuotes(body []byte) ([]DollarQuote, error) {
+	var dollarQuotes []DollarQuote
+	if err := json.Unmarshal(body, &dollarQuotes); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidQuote, err)
+	}
+
+	for _, quote := range dollarQuotes {
+		if err := quote.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return dollarQuotes, nil
+}
+
+func (d DollarQuote) Validate() error {
+	switch {
+	case d.Code == "" || d.Codein == "":
+		return fmt.Errorf("%w: missing currency code", ErrInvalidQuote)
+	case !d.Bid.IsPositive():
+		return fmt.Errorf("%w: bid must be positive, got %s", ErrInvalidQuote, d.Bid)
+	case !d.Ask.IsPositive():
+		return fmt.Errorf("%w: ask must be positive, got %s", ErrInvalidQuote, d.Ask)
+	case d.High.LessThan(d.Low):
+		return fmt.Errorf("%w: high %s is lower than low %s", ErrInvalidQuote, d.High, d.Low)
+	case d.Timestamp <= 0:
+		return fmt.Errorf("%w: missing timestamp", ErrInvalidQuote)
+	}
+	return nil
+}
+
+// Time returns the upstream Timestamp as a time.Time.
+func (d DollarQuote) Time() time.Time {
+	return time.Unix(d.Timestamp, 0)
+}
+
+// Pair returns the quote currency pair in the "CODE-CODEIN" format used by the upstream API.
+func (d DollarQuote) Pair() string {
+	return d.Code + "-" + d.Codein
+}
+
+// NormalizePair uppercases and trims a pair so "usd-brl " and "USD-BRL" are the same pair.
+func NormalizePair(pair string) string {
+	return strings.ToUpper(strings.TrimSpace(pair))
+}
+
+// ParseTime accepts unix seconds, RFC3339 or a plain date (YYYY-MM-DD, UTC); empty is
+// the zero time.
+func ParseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if unix, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, v)
+}
+
+func IsSupportedPair(pair string) bool {
+	pair = NormalizePair(pair)
+	for _, p := range SupportedPairs {
+		if p == pair {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,47 @@
+package quotes
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const payload = `[{"code":"USD","codein":"BRL","name":"Dólar Americano/Real Brasileiro","high":"5.4012","low":"5.3001","varBid":"-0.0125","pctChange":"-0.23","bid":"5.35","ask":"5.351","timestamp":"1717171200","create_date":"2024-05-31 13:00:00"}]`
+
+func TestParseDollarQuotes(t *testing.T) {
+	t.Run("should parse typed quote", func(t *testing.T) {
+		quotes, err := ParseDollarQuotes([]byte(payload))
+		assert.NoError(t, err)
+		assert.Len(t, quotes, 1)
+
+		quote := quotes[0]
+		assert.Equal(t, "USD-BRL", quote.Pair())
+		assert.Equal(t, "5.35", quote.Bid.String())
+		assert.Equal(t, "-0.0125", quote.VarBid.String())
+		assert.Equal(t, int64(1717171200), quote.Timestamp)
+	})
+
+	t.Run("should keep upstream encoding", func(t *testing.T) {
+		quotes, err := ParseDollarQuotes([]byte(payload))
+		assert.NoError(t, err)
+
+		encoded, err := json.Marshal(quotes)
+		assert.NoError(t, err)
+		assert.JSONEq(t, payload, string(encoded))
+	})
+
+	t.Run("should reject invalid payloads", func(t *testing.T) {
+		for _, body := range []string{
+			`{"USDBRL":{}}`,
+			`[{"code":"USD","codein":"BRL","bid":"abc","ask":"5.35","timestamp":"1717171200"}]`,
+			`[{"code":"USD","codein":"BRL","bid":"0","ask":"5.35","timestamp":"1717171200"}]`,
+			`[{"code":"USD","codein":"BRL","bid":"5.35","ask":"5.36","high":"5.0","low":"5.5","timestamp":"1717171200"}]`,
+			`[{"code":"","codein":"BRL","bid":"5.35","ask":"5.36","timestamp":"1717171200"}]`,
+			`[{"code":"USD","codein":"BRL","bid":"5.35","ask":"5.36"}]`,
+		} {
+			_, err := ParseDollarQuotes([]byte(body))
+			assert.ErrorContains(t, err, InvalidQuoteError, body)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+)
+
+// AwesomeAPIProvider reads quotes from economia.awesomeapi.com.br, which serves every
+// pair in SupportedPairs.
+type AwesomeAPIProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewAwesomeAPI returns the awesomeapi provider. The pair is appended to baseURL,
+// DefaultAwesomeAPIURL when empty.
+func NewAwesomeAPI(baseURL string, client *http.Client) *AwesomeAPIProvider {
+	if baseURL == "" {
+		baseURL = DefaultAwesomeAPIURL
+	}
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	return &AwesomeAPIProvider{
+		baseURL: baseURL,
+		client:  client,
+	}
+}
+
+func (p *AwesomeAPIProvider) Name() string {
+	return AwesomeAPI
+}
+
+func (p *AwesomeAPIProvider) FetchQuote(ctx context.Context, pair string) (q.DollarQuote, error) {
+	body, err := get(ctx, p.client, p.baseURL+q.NormalizePair(pair))
+	if err != nil {
+		return q.DollarQuote{}, err
+	}
+
+	dollarQuotes, err := q.ParseDollarQuotes(body)
+	if err != nil {
+		return q.DollarQuote{}, err
+	}
+
+	if len(dollarQuotes) == 0 {
+		return q.DollarQuote{}, fmt.Errorf("%w: no quotes found", q.ErrInvalidQuote)
+	}
+
+	return dollarQuotes[0], nil
+}
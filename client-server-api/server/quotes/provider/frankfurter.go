@@ -0,0 +1,91 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+
+	"github.com/shopspring/decimal"
+)
+
+type (
+	// FrankfurterProvider reads the daily ECB reference rates from frankfurter.app. It
+	// has no bid/ask spread nor crypto currencies, so it fits as a fallback for fiat
+	// pairs only; its quotes are marked Reference.
+	FrankfurterProvider struct {
+		baseURL string
+		client  *http.Client
+	}
+
+	frankfurterResponse struct {
+		Amount decimal.Decimal            `json:"amount"`
+		Base   string                     `json:"base"`
+		Date   string                     `json:"date"`
+		Rates  map[string]decimal.Decimal `json:"rates"`
+	}
+)
+
+// NewFrankfurter returns the frankfurter provider. baseURL is the "latest" endpoint,
+// DefaultFrankfurterURL when empty.
+func NewFrankfurter(baseURL string, client *http.Client) *FrankfurterProvider {
+	if baseURL == "" {
+		baseURL = DefaultFrankfurterURL
+	}
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	return &FrankfurterProvider{
+		baseURL: baseURL,
+		client:  client,
+	}
+}
+
+func (p *FrankfurterProvider) Name() string {
+	return Frankfurter
+}
+
+func (p *FrankfurterProvider) FetchQuote(ctx context.Context, pair string) (q.DollarQuote, error) {
+	code, codein, err := splitPair(pair)
+	if err != nil {
+		return q.DollarQuote{}, err
+	}
+
+	body, err := get(ctx, p.client, p.baseURL+"?"+url.Values{"from": {code}, "to": {codein}}.Encode())
+	if err != nil {
+		return q.DollarQuote{}, err
+	}
+
+	var res frankfurterResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return q.DollarQuote{}, fmt.Errorf("%w: %w", q.ErrInvalidQuote, err)
+	}
+
+	rate, ok := res.Rates[codein]
+	if !ok {
+		return q.DollarQuote{}, fmt.Errorf("%w: no %s rate in response", q.ErrInvalidQuote, codein)
+	}
+	day, err := time.Parse(time.DateOnly, res.Date)
+	if err != nil {
+		return q.DollarQuote{}, fmt.Errorf("%w: %w", q.ErrInvalidQuote, err)
+	}
+
+	quote := q.DollarQuote{
+		Code:       code,
+		Codein:     codein,
+		Name:       code + "/" + codein + " (ECB reference rate)",
+		High:       rate,
+		Low:        rate,
+		Bid:        rate,
+		Ask:        rate,
+		Timestamp:  day.Unix(),
+		CreateDate: res.Date,
+		Reference:  true,
+	}
+	return quote, quote.Validate()
+}
@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+)
+
+const (
+	AwesomeAPI  = "awesomeapi"
+	Frankfurter = "frankfurter"
+
+	StrategyFailover = "failover"
+	StrategyRace     = "race"
+
+	DefaultAwesomeAPIURL  = "https://economia.awesomeapi.com.br/json/"
+	DefaultFrankfurterURL = "https://api.frankfurter.app/latest"
+)
+
+type (
+	// QuoteProvider fetches the current quote of a pair from one upstream. Providers
+	// must stop as soon as ctx is done.
+	QuoteProvider interface {
+		Name() string
+		FetchQuote(ctx context.Context, pair string) (q.DollarQuote, error)
+	}
+
+	Config struct {
+		// Names lists the providers to use, in priority order.
+		Names          []string
+		Strategy       string
+		AttemptTimeout time.Duration
+		AwesomeAPIURL  string
+		FrankfurterURL string
+		Client         *http.Client
+	}
+)
+
+// New builds the provider described by cfg: a single provider, or a Failover/Race
+// over several of them.
+func New(cfg Config) (QuoteProvider, error) {
+	if len(cfg.Names) == 0 {
+		cfg.Names = []string{AwesomeAPI}
+	}
+
+	providers := make([]QuoteProvider, 0, len(cfg.Names))
+	for _, name := range cfg.Names {
+		switch strings.TrimSpace(name) {
+		case AwesomeAPI:
+			providers = append(providers, NewAwesomeAPI(cfg.AwesomeAPIURL, cfg.Client))
+		case Frankfurter:
+			providers = append(providers, NewFrankfurter(cfg.FrankfurterURL, cfg.Client))
+		default:
+			return nil, fmt.Errorf("unknown quote provider %q", name)
+		}
+	}
+
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+
+	switch cfg.Strategy {
+	case "", StrategyFailover:
+		return NewFailover(cfg.AttemptTimeout, providers...), nil
+	case StrategyRace:
+		return NewRace(providers...), nil
+	default:
+		return nil, fmt.Errorf("unknown provider strategy %q", cfg.Strategy)
+	}
+}
+
+// get returns the body of a GET request to url, read within ctx.
+func get(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	log.Println("res", url, res.Status)
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upstream returned %s", res.Status)
+	}
+
+	return io.ReadAll(res.Body)
+}
+
+func splitPair(pair string) (string, string, error) {
+	code, codein, ok := strings.Cut(q.NormalizePair(pair), "-")
+	if !ok || code == "" || codein == "" {
+		return "", "", q.ErrUnsupportedPair
+	}
+	return code, codein, nil
+}
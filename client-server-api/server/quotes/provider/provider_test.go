@@ -0,0 +1,232 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+const awesomePayload = `[{"code":"USD","codein":"BRL","name":"Dólar Americano/Real Brasileiro","high":"5.40","low":"5.30","varBid":"0.01","pctChange":"0.2","bid":"5.35","ask":"5.36","timestamp":"1717171200","create_date":"2024-05-31 13:00:00"}]`
+
+// stub is a QuoteProvider answering with bid after delay, or with err.
+type stub struct {
+	name  string
+	delay time.Duration
+	bid   string
+	err   error
+}
+
+func (s stub) Name() string { return s.name }
+
+func (s stub) FetchQuote(ctx context.Context, pair string) (q.DollarQuote, error) {
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return q.DollarQuote{}, ctx.Err()
+	}
+	if s.err != nil {
+		return q.DollarQuote{}, s.err
+	}
+	return q.DollarQuote{Code: "USD", Codein: "BRL", Bid: decimal.RequireFromString(s.bid)}, nil
+}
+
+func newServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAwesomeAPI(t *testing.T) {
+	t.Run("should fetch quote of pair", func(t *testing.T) {
+		var path string
+		server := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			w.Write([]byte(awesomePayload))
+		})
+
+		quote, err := NewAwesomeAPI(server.URL+"/json/", nil).FetchQuote(context.Background(), "usd-brl")
+		assert.NoError(t, err)
+		assert.Equal(t, "/json/USD-BRL", path)
+		assert.Equal(t, "5.35", quote.Bid.String())
+	})
+
+	t.Run("should fail on upstream error status", func(t *testing.T) {
+		server := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, `{"status":404,"code":"CoinNotExists"}`, http.StatusNotFound)
+		})
+
+		_, err := NewAwesomeAPI(server.URL+"/json/", nil).FetchQuote(context.Background(), "USD-BRL")
+		assert.ErrorContains(t, err, "404")
+	})
+
+	t.Run("should fail on invalid payload", func(t *testing.T) {
+		server := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"USDBRL":{}}`))
+		})
+
+		_, err := NewAwesomeAPI(server.URL+"/json/", nil).FetchQuote(context.Background(), "USD-BRL")
+		assert.ErrorContains(t, err, q.InvalidQuoteError)
+	})
+}
+
+func TestFrankfurter(t *testing.T) {
+	t.Run("should map reference rate to quote", func(t *testing.T) {
+		var query string
+		server := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.RawQuery
+			w.Write([]byte(`{"amount":1.0,"base":"USD","date":"2024-05-31","rates":{"BRL":5.2143}}`))
+		})
+
+		quote, err := NewFrankfurter(server.URL, nil).FetchQuote(context.Background(), "USD-BRL")
+		assert.NoError(t, err)
+		assert.Equal(t, "from=USD&to=BRL", query)
+		assert.Equal(t, "USD-BRL", quote.Pair())
+		assert.Equal(t, "5.2143", quote.Bid.String())
+		assert.Equal(t, "5.2143", quote.Ask.String())
+		assert.Equal(t, time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC).Unix(), quote.Timestamp)
+		assert.True(t, quote.Reference)
+	})
+
+	t.Run("should fail when rate is missing", func(t *testing.T) {
+		server := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"amount":1.0,"base":"USD","date":"2024-05-31","rates":{}}`))
+		})
+
+		_, err := NewFrankfurter(server.URL, nil).FetchQuote(context.Background(), "USD-BRL")
+		assert.ErrorContains(t, err, q.InvalidQuoteError)
+	})
+}
+
+func TestFailover(t *testing.T) {
+	t.Run("should use first provider that answers", func(t *testing.T) {
+		failover := NewFailover(0,
+			stub{name: "broken", err: errors.New("boom")},
+			stub{name: "second", bid: "5.2"},
+			stub{name: "third", bid: "5.3"},
+		)
+
+		quote, err := failover.FetchQuote(context.Background(), "USD-BRL")
+		assert.NoError(t, err)
+		assert.Equal(t, "5.2", quote.Bid.String())
+	})
+
+	t.Run("should bound each attempt", func(t *testing.T) {
+		failover := NewFailover(20*time.Millisecond,
+			stub{name: "hanging", delay: time.Second, bid: "5.1"},
+			stub{name: "second", bid: "5.2"},
+		)
+
+		quote, err := failover.FetchQuote(context.Background(), "USD-BRL")
+		assert.NoError(t, err)
+		assert.Equal(t, "5.2", quote.Bid.String())
+	})
+
+	t.Run("should report every failure", func(t *testing.T) {
+		failover := NewFailover(0,
+			stub{name: "first", err: errors.New("boom")},
+			stub{name: "second", err: errors.New("bang")},
+		)
+
+		_, err := failover.FetchQuote(context.Background(), "USD-BRL")
+		assert.ErrorContains(t, err, "first: boom")
+		assert.ErrorContains(t, err, "second: bang")
+	})
+
+	t.Run("should fail over between real providers", func(t *testing.T) {
+		down := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		})
+		frankfurter := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"amount":1.0,"base":"USD","date":"2024-05-31","rates":{"BRL":5.2143}}`))
+		})
+
+		provider, err := New(Config{
+			Names:          []string{AwesomeAPI, Frankfurter},
+			AwesomeAPIURL:  down.URL + "/json/",
+			FrankfurterURL: frankfurter.URL,
+		})
+		assert.NoError(t, err)
+
+		quote, err := provider.FetchQuote(context.Background(), "USD-BRL")
+		assert.NoError(t, err)
+		assert.Equal(t, "5.2143", quote.Bid.String())
+	})
+}
+
+func TestRace(t *testing.T) {
+	t.Run("should return fastest quote", func(t *testing.T) {
+		race := NewRace(
+			stub{name: "slow", delay: 200 * time.Millisecond, bid: "5.1"},
+			stub{name: "fast", delay: 5 * time.Millisecond, bid: "5.2"},
+		)
+
+		start := time.Now()
+		quote, err := race.FetchQuote(context.Background(), "USD-BRL")
+		assert.NoError(t, err)
+		assert.Equal(t, "5.2", quote.Bid.String())
+		assert.Less(t, time.Since(start), 200*time.Millisecond)
+	})
+
+	t.Run("should ignore fast failures", func(t *testing.T) {
+		race := NewRace(
+			stub{name: "broken", err: errors.New("boom")},
+			stub{name: "slow", delay: 20 * time.Millisecond, bid: "5.1"},
+		)
+
+		quote, err := race.FetchQuote(context.Background(), "USD-BRL")
+		assert.NoError(t, err)
+		assert.Equal(t, "5.1", quote.Bid.String())
+	})
+
+	t.Run("should cancel slower providers", func(t *testing.T) {
+		cancelled := make(chan struct{})
+		slow := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			close(cancelled)
+		})
+		fast := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(awesomePayload))
+		})
+
+		race := NewRace(NewAwesomeAPI(slow.URL+"/", nil), NewAwesomeAPI(fast.URL+"/", nil))
+		_, err := race.FetchQuote(context.Background(), "USD-BRL")
+		assert.NoError(t, err)
+
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("slow provider request was not cancelled")
+		}
+	})
+
+	t.Run("should fail when every provider fails", func(t *testing.T) {
+		race := NewRace(
+			stub{name: "first", err: errors.New("boom")},
+			stub{name: "second", err: errors.New("bang")},
+		)
+
+		_, err := race.FetchQuote(context.Background(), "USD-BRL")
+		assert.ErrorContains(t, err, "first: boom")
+		assert.ErrorContains(t, err, "second: bang")
+	})
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{Names: []string{"unknown"}})
+	assert.Error(t, err)
+
+	_, err = New(Config{Names: []string{AwesomeAPI, Frankfurter}, Strategy: "random"})
+	assert.Error(t, err)
+
+	p, err := New(Config{Names: []string{AwesomeAPI, Frankfurter}, Strategy: StrategyRace})
+	assert.NoError(t, err)
+	assert.Equal(t, "race(awesomeapi,frankfurter)", p.Name())
+}
@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+)
+
+type (
+	// Failover asks each provider in priority order and returns the first quote.
+	Failover struct {
+		attemptTimeout time.Duration
+		providers      []QuoteProvider
+	}
+
+	// Race asks every provider at once, returns the fastest quote and cancels the
+	// slower requests.
+	Race struct {
+		providers []QuoteProvider
+	}
+
+	raceResult struct {
+		provider string
+		quote    q.DollarQuote
+		err      error
+	}
+)
+
+// NewFailover returns a Failover over providers. A non zero attemptTimeout bounds each
+// attempt, so a hanging provider leaves time for the next ones.
+func NewFailover(attemptTimeout time.Duration, providers ...QuoteProvider) *Failover {
+	return &Failover{
+		attemptTimeout: attemptTimeout,
+		providers:      providers,
+	}
+}
+
+func (f *Failover) Name() string {
+	return StrategyFailover + "(" + names(f.providers) + ")"
+}
+
+func (f *Failover) FetchQuote(ctx context.Context, pair string) (q.DollarQuote, error) {
+	var errs []error
+	for _, provider := range f.providers {
+		quote, err := f.attempt(ctx, provider, pair)
+		if err == nil {
+			return quote, nil
+		}
+
+		log.Printf("provider %s failed for %s: %v", provider.Name(), pair, err)
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return q.DollarQuote{}, errors.Join(errs...)
+}
+
+func (f *Failover) attempt(ctx context.Context, provider QuoteProvider, pair string) (q.DollarQuote, error) {
+	if f.attemptTimeout <= 0 {
+		return provider.FetchQuote(ctx, pair)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.attemptTimeout)
+	defer cancel()
+	return provider.FetchQuote(ctx, pair)
+}
+
+func NewRace(providers ...QuoteProvider) *Race {
+	return &Race{providers: providers}
+}
+
+func (r *Race) Name() string {
+	return StrategyRace + "(" + names(r.providers) + ")"
+}
+
+func (r *Race) FetchQuote(c context.Context, pair string) (q.DollarQuote, error) {
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	// buffered so the losers never block after the winner returned
+	ch := make(chan raceResult, len(r.providers))
+	for _, provider := range r.providers {
+		go func(provider QuoteProvider) {
+			quote, err := provider.FetchQuote(ctx, pair)
+			ch <- raceResult{provider: provider.Name(), quote: quote, err: err}
+		}(provider)
+	}
+
+	var errs []error
+	for range r.providers {
+		result := <-ch
+		if result.err == nil {
+			log.Printf("provider %s won the race for %s", result.provider, pair)
+			return result.quote, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", result.provider, result.err))
+	}
+
+	return q.DollarQuote{}, errors.Join(errs...)
+}
+
+func names(providers []QuoteProvider) string {
+	names := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		names = append(names, provider.Name())
+	}
+	return strings.Join(names, ",")
+}
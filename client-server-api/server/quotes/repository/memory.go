@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+
+	"github.com/shopspring/decimal"
+)
+
+// Memory is a DollarQuoteRepository kept in process memory, meant for tests and local
+// runs without a database file. It honours the same timeouts as Repository.
+type Memory struct {
+	mu     sync.RWMutex
+	quotes []q.DollarQuote
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) CreateDollarQuote(c context.Context, quote q.DollarQuote, t time.Duration) error {
+	ctx, cancel := context.WithTimeout(c, t)
+	defer cancel()
+
+	// an expired budget stores nothing, as a rolled back transaction
+	if ctx.Err() != nil {
+		return timeoutError(c)
+	}
+
+	m.mu.Lock()
+	m.quotes = append(m.quotes, quote)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Memory) CreateDollarQuotes(c context.Context, quotes []q.DollarQuote, t time.Duration) error {
+	ctx, cancel := context.WithTimeout(c, t)
+	defer cancel()
+
+	// an expired budget stores nothing, as a rolled back transaction
+	if ctx.Err() != nil {
+		return timeoutError(c)
+	}
+
+	m.mu.Lock()
+	m.quotes = append(m.quotes, quotes...)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Memory) ListDollarQuotes(c context.Context, filter q.HistoryFilter, t time.Duration) ([]q.DollarQuote, error) {
+	ctx, cancel := context.WithTimeout(c, t)
+	defer cancel()
+
+	quotes := m.filter(q.NormalizePair(filter.Pair), filter.From, filter.To)
+	sort.SliceStable(quotes, func(i, j int) bool { return quotes[i].Timestamp > quotes[j].Timestamp })
+
+	if filter.Offset >= len(quotes) {
+		quotes = quotes[:0]
+	} else {
+		quotes = quotes[filter.Offset:]
+	}
+	if len(quotes) > filter.Limit {
+		quotes = quotes[:filter.Limit]
+	}
+
+	if ctx.Err() != nil {
+		return nil, timeoutError(c)
+	}
+	return quotes, nil
+}
+
+// ExportDollarQuotes calls fn with a snapshot of the quotes matching filter, oldest first.
+func (m *Memory) ExportDollarQuotes(c context.Context, filter q.ExportFilter, fn func(q.DollarQuote) error) error {
+	quotes := m.filter(q.NormalizePair(filter.Pair), filter.From, filter.To)
+	sort.SliceStable(quotes, func(i, j int) bool { return quotes[i].Timestamp < quotes[j].Timestamp })
+
+	for _, quote := range quotes {
+		if err := c.Err(); err != nil {
+			return err
+		}
+		if err := fn(quote); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Memory) FindLatestDollarQuote(c context.Context, pair string, t time.Duration) (*q.DollarQuote, error) {
+	return findLatest(c, m, pair, t)
+}
+
+func (m *Memory) AggregateDollarQuotes(c context.Context, filter q.CandleFilter, t time.Duration) ([]q.Candle, error) {
+	interval, ok := q.CandleIntervals[filter.Interval]
+	if !ok {
+		return nil, q.ErrUnsupportedInterval
+	}
+	bucketSize := int64(interval / time.Second)
+
+	ctx, cancel := context.WithTimeout(c, t)
+	defer cancel()
+
+	quotes := m.filter(q.NormalizePair(filter.Pair), filter.From, filter.To)
+	sort.SliceStable(quotes, func(i, j int) bool { return quotes[i].Timestamp < quotes[j].Timestamp })
+
+	candles := []q.Candle{}
+	spreads := decimal.Zero
+	for _, quote := range quotes {
+		start := time.Unix(quote.Timestamp/bucketSize*bucketSize, 0).UTC()
+		if len(candles) == 0 || !candles[len(candles)-1].Start.Equal(start) {
+			if len(candles) > 0 {
+				closeCandle(&candles[len(candles)-1], spreads)
+			}
+			candles = append(candles, q.Candle{
+				Pair:     q.NormalizePair(filter.Pair),
+				Interval: filter.Interval,
+				Start:    start,
+				Open:     quote.Bid,
+				High:     quote.Bid,
+				Low:      quote.Bid,
+			})
+			spreads = decimal.Zero
+		}
+
+		candle := &candles[len(candles)-1]
+		candle.High = decimal.Max(candle.High, quote.Bid)
+		candle.Low = decimal.Min(candle.Low, quote.Bid)
+		candle.Close = quote.Bid
+		candle.Count++
+		spreads = spreads.Add(quote.Ask.Sub(quote.Bid))
+	}
+	if len(candles) > 0 {
+		closeCandle(&candles[len(candles)-1], spreads)
+	}
+	// keep the newest buckets
+	if len(candles) > filter.Limit {
+		candles = candles[len(candles)-max(filter.Limit, 0):]
+	}
+
+	if ctx.Err() != nil {
+		return nil, timeoutError(c)
+	}
+	return candles, nil
+}
+
+func closeCandle(candle *q.Candle, spreads decimal.Decimal) {
+	candle.AvgSpread = spreads.Div(decimal.NewFromInt(int64(candle.Count))).Round(spreadPrecision)
+}
+
+// filter returns a copy of the stored quotes matching pair (any when empty) and the
+// closed [from, to] range (open on zero bounds).
+func (m *Memory) filter(pair string, from, to time.Time) []q.DollarQuote {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	quotes := []q.DollarQuote{}
+	for _, quote := range m.quotes {
+		switch {
+		case pair != "" && quote.Pair() != pair:
+		case !from.IsZero() && quote.Timestamp < from.Unix():
+		case !to.IsZero() && quote.Timestamp > to.Unix():
+		default:
+			quotes = append(quotes, quote)
+		}
+	}
+	return quotes
+}
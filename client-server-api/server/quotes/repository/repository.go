@@ -4,37 +4,262 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	mydb "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/database"
 	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
 )
 
-const TimeoutError = "DB_OPERATION_TIMEOUT"
+const (
+	TimeoutError  = "DB_OPERATION_TIMEOUT"
+	NotFoundError = "QUOTE_NOT_FOUND"
+
+	spreadPrecision = 8
+)
+
+var (
+	ErrTimeout  = errors.New(TimeoutError)
+	ErrNotFound = errors.New(NotFoundError)
+)
 
 type (
 	CreaterDollarQuote interface {
 		CreateDollarQuote(c context.Context, quote q.DollarQuote, t time.Duration) error
 	}
 
+	BatchCreaterDollarQuote interface {
+		CreateDollarQuotes(c context.Context, quotes []q.DollarQuote, t time.Duration) error
+	}
+
+	ListerDollarQuote interface {
+		ListDollarQuotes(c context.Context, filter q.HistoryFilter, t time.Duration) ([]q.DollarQuote, error)
+	}
+
+	AggregatorDollarQuote interface {
+		AggregateDollarQuotes(c context.Context, filter q.CandleFilter, t time.Duration) ([]q.Candle, error)
+	}
+
+	FinderLatestDollarQuote interface {
+		FindLatestDollarQuote(c context.Context, pair string, t time.Duration) (*q.DollarQuote, error)
+	}
+
+	ExporterDollarQuote interface {
+		ExportDollarQuotes(c context.Context, filter q.ExportFilter, fn func(q.DollarQuote) error) error
+	}
+
+	DollarQuoteRepository interface {
+		CreaterDollarQuote
+		BatchCreaterDollarQuote
+		ListerDollarQuote
+		AggregatorDollarQuote
+		FinderLatestDollarQuote
+		ExporterDollarQuote
+	}
+
 	Repository struct {
-		ctx context.Context
-		db  *sql.DB
+		db      *sql.DB
+		dialect mydb.Dialect
 	}
 )
 
-func New(ctx context.Context, db *sql.DB) *Repository {
+// New returns a repository over a SQLite connection.
+func New(db *sql.DB) *Repository {
+	return NewWithDialect(db, mydb.SQLite)
+}
+
+// NewWithDialect returns a repository over any connection opened by the database package,
+// e.g. NewWithDialect(client.GetConnection(), client.Dialect()).
+func NewWithDialect(db *sql.DB, dialect mydb.Dialect) *Repository {
 	return &Repository{
-		ctx: ctx,
-		db:  db,
+		db:      db,
+		dialect: dialect,
+	}
+}
+
+const insertDollarQuote = `
+    INSERT INTO dollar_quote (
+      Code,
+      Codein,
+      Name,
+      High,
+      Low,
+      VarBid,
+      PctChange,
+      Bid,
+      Ask,
+      Timestamp,
+      CreateDate,
+      Pair
+    ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+  `
+
+func insertArgs(quote q.DollarQuote) []any {
+	return []any{quote.Code, quote.Codein, quote.Name, quote.High, quote.Low, quote.VarBid, quote.PctChange, quote.Bid, quote.Ask, quote.Timestamp, quote.CreateDate, quote.Pair()}
+}
+
+// timeoutError is the error of an operation whose context, derived from c, is done: the
+// error of c when the caller went away, ErrTimeout when the operation budget ran out.
+func timeoutError(c context.Context) error {
+	if err := c.Err(); err != nil {
+		return err
 	}
+	return ErrTimeout
 }
 
 func (r *Repository) CreateDollarQuote(c context.Context, quote q.DollarQuote, t time.Duration) error {
 	ctx, cancel := context.WithTimeout(c, t)
 	defer cancel()
 
-	_, err := r.db.ExecContext(ctx, `
-    INSERT INTO dollar_quote (
+	_, err := r.db.ExecContext(ctx, r.dialect.Rebind(insertDollarQuote), insertArgs(quote)...)
+
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return timeoutError(c)
+	default:
+		return err
+	}
+}
+
+// CreateDollarQuotes stores quotes in a single transaction: either all of them are
+// written or none is.
+func (r *Repository) CreateDollarQuotes(c context.Context, quotes []q.DollarQuote, t time.Duration) error {
+	ctx, cancel := context.WithTimeout(c, t)
+	defer cancel()
+
+	err := r.createInTx(ctx, quotes)
+
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return timeoutError(c)
+	default:
+		return err
+	}
+}
+
+func (r *Repository) createInTx(ctx context.Context, quotes []q.DollarQuote) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, r.dialect.Rebind(insertDollarQuote))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, quote := range quotes {
+		if _, err := stmt.ExecContext(ctx, insertArgs(quote)...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListDollarQuotes returns stored quotes newest first, filtered by pair and by the upstream
+// Timestamp (unix seconds) falling inside [filter.From, filter.To].
+func (r *Repository) ListDollarQuotes(c context.Context, filter q.HistoryFilter, t time.Duration) ([]q.DollarQuote, error) {
+	ctx, cancel := context.WithTimeout(c, t)
+	defer cancel()
+
+	query, args := selectQuotes(filter.Pair, filter.From, filter.To)
+	query += " ORDER BY Timestamp DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, r.dialect.Rebind(query), args...)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, timeoutError(c)
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	quotes := []q.DollarQuote{}
+	for rows.Next() {
+		quote, err := scanQuote(rows)
+		if err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, quote)
+	}
+
+	switch err := rows.Err(); {
+	case err == nil:
+		return quotes, nil
+	case ctx.Err() != nil:
+		return nil, timeoutError(c)
+	default:
+		return nil, err
+	}
+}
+
+// ExportDollarQuotes calls fn with every stored quote matching filter, oldest first,
+// reading them one row at a time. There is no budget: the export lasts until c is done
+// or fn returns an error, which is returned as is.
+func (r *Repository) ExportDollarQuotes(c context.Context, filter q.ExportFilter, fn func(q.DollarQuote) error) error {
+	query, args := selectQuotes(filter.Pair, filter.From, filter.To)
+	query += " ORDER BY Timestamp"
+
+	rows, err := r.db.QueryContext(c, r.dialect.Rebind(query), args...)
+	if err != nil {
+		if c.Err() != nil {
+			return c.Err()
+		}
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		quote, err := scanQuote(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(quote); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		if c.Err() != nil {
+			return c.Err()
+		}
+		return err
+	}
+	return nil
+}
+
+// selectQuotes returns the query of the quotes of pair (any when empty) with their
+// Timestamp in [from, to] (open on zero bounds), and its arguments.
+func selectQuotes(pair string, from, to time.Time) (string, []any) {
+	var (
+		where []string
+		args  []any
+	)
+	if pair != "" {
+		where = append(where, "Pair = ?")
+		args = append(args, q.NormalizePair(pair))
+	}
+	if !from.IsZero() {
+		where = append(where, "Timestamp >= ?")
+		args = append(args, from.Unix())
+	}
+	if !to.IsZero() {
+		where = append(where, "Timestamp <= ?")
+		args = append(args, to.Unix())
+	}
+
+	query := `
+    SELECT
       Code,
       Codein,
       Name,
@@ -46,15 +271,121 @@ func (r *Repository) CreateDollarQuote(c context.Context, quote q.DollarQuote, t
       Ask,
       Timestamp,
       CreateDate
-    ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-  `, quote.Code, quote.Codein, quote.Name, quote.High, quote.Low, quote.VarBid, quote.PctChange, quote.Bid, quote.Ask, quote.Timestamp, quote.CreateDate)
+    FROM dollar_quote`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	return query, args
+}
 
-	switch {
+func scanQuote(rows *sql.Rows) (q.DollarQuote, error) {
+	var quote q.DollarQuote
+	err := rows.Scan(&quote.Code, &quote.Codein, &quote.Name, &quote.High, &quote.Low, &quote.VarBid, &quote.PctChange, &quote.Bid, &quote.Ask, &quote.Timestamp, &quote.CreateDate)
+	return quote, err
+}
+
+// FindLatestDollarQuote returns the stored quote of pair with the newest Timestamp, or
+// NotFoundError when the pair was never stored.
+func (r *Repository) FindLatestDollarQuote(c context.Context, pair string, t time.Duration) (*q.DollarQuote, error) {
+	return findLatest(c, r, pair, t)
+}
+
+func findLatest(c context.Context, lister ListerDollarQuote, pair string, t time.Duration) (*q.DollarQuote, error) {
+	quotes, err := lister.ListDollarQuotes(c, q.HistoryFilter{Pair: pair, Limit: 1}, t)
+	if err != nil {
+		return nil, err
+	}
+	if len(quotes) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &quotes[0], nil
+}
+
+// AggregateDollarQuotes groups the quotes of filter.Pair into epoch aligned buckets of
+// filter.Interval and returns one candle per non empty bucket, oldest first. When there
+// are more than filter.Limit buckets, the newest ones are kept.
+func (r *Repository) AggregateDollarQuotes(c context.Context, filter q.CandleFilter, t time.Duration) ([]q.Candle, error) {
+	interval, ok := q.CandleIntervals[filter.Interval]
+	if !ok {
+		return nil, q.ErrUnsupportedInterval
+	}
+	bucketSize := int64(interval / time.Second)
+
+	ctx, cancel := context.WithTimeout(c, t)
+	defer cancel()
+
+	where := []string{"Pair = ?"}
+	args := []any{bucketSize, bucketSize, q.NormalizePair(filter.Pair)}
+	if !filter.From.IsZero() {
+		where = append(where, "Timestamp >= ?")
+		args = append(args, filter.From.Unix())
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "Timestamp <= ?")
+		args = append(args, filter.To.Unix())
+	}
+	args = append(args, filter.Limit)
+
+	rows, err := r.db.QueryContext(ctx, r.dialect.Rebind(`
+    SELECT
+      Bucket,
+      MIN(Open),
+      MAX(Bid),
+      MIN(Bid),
+      MIN(Close),
+      AVG(Ask - Bid),
+      COUNT(*)
+    FROM (
+      SELECT
+        (Timestamp / ?) * ? AS Bucket,
+        Bid,
+        Ask,
+        FIRST_VALUE(Bid) OVER bucket AS Open,
+        LAST_VALUE(Bid) OVER bucket AS Close
+      FROM dollar_quote
+      WHERE `+strings.Join(where, " AND ")+`
+      WINDOW bucket AS (
+        PARTITION BY (Timestamp / `+strconv.FormatInt(bucketSize, 10)+`)
+        ORDER BY Timestamp
+        ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING
+      )
+    ) AS bucketed
+    GROUP BY Bucket
+    ORDER BY Bucket DESC
+    LIMIT ?
+  `), args...)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, timeoutError(c)
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	candles := []q.Candle{}
+	for rows.Next() {
+		var (
+			bucket int64
+			candle = q.Candle{Pair: q.NormalizePair(filter.Pair), Interval: filter.Interval}
+		)
+		err := rows.Scan(&bucket, &candle.Open, &candle.High, &candle.Low, &candle.Close, &candle.AvgSpread, &candle.Count)
+		if err != nil {
+			return nil, err
+		}
+		candle.Start = time.Unix(bucket, 0).UTC()
+		// the average is computed over REAL columns, drop the binary float noise
+		candle.AvgSpread = candle.AvgSpread.Round(spreadPrecision)
+		candles = append(candles, candle)
+	}
+
+	switch err := rows.Err(); {
 	case err == nil:
-		return nil
+		slices.Reverse(candles)
+		return candles, nil
 	case ctx.Err() != nil:
-		return errors.New(TimeoutError)
+		return nil, timeoutError(c)
 	default:
-		return err
+		return nil, err
 	}
 }
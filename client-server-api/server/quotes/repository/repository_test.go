@@ -0,0 +1,292 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mydb "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/database"
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+var d = decimal.RequireFromString
+
+func newQuote(code, codein, bid, ask string, timestamp int64) q.DollarQuote {
+	return q.DollarQuote{Code: code, Codein: codein, Bid: d(bid), Ask: d(ask), High: d(bid), Low: d(bid), Timestamp: timestamp}
+}
+
+// backends lists every DollarQuoteRepository implementation. Postgres only runs when
+// POSTGRES_DSN points to a disposable database.
+var backends = map[string]func(t *testing.T) DollarQuoteRepository{
+	"sqlite": func(t *testing.T) DollarQuoteRepository {
+		db, err := mydb.New(mydb.Config{File: filepath.Join(t.TempDir(), "sqlite.s3db"), RunMigration: true})
+		assert.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		return New(db.GetConnection())
+	},
+	"memory": func(t *testing.T) DollarQuoteRepository {
+		return NewMemory()
+	},
+	"postgres": func(t *testing.T) DollarQuoteRepository {
+		dsn := os.Getenv("POSTGRES_DSN")
+		if dsn == "" {
+			t.Skip("POSTGRES_DSN not set")
+		}
+		ctx := context.Background()
+		db, err := mydb.NewPostgres(ctx, mydb.PostgresConfig{DSN: dsn, RunMigration: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		_, err = db.GetConnection().Exec(`TRUNCATE dollar_quote`)
+		assert.NoError(t, err)
+		return NewWithDialect(db.GetConnection(), db.Dialect())
+	},
+}
+
+func forEachBackend(t *testing.T, quotes []q.DollarQuote, test func(t *testing.T, repo DollarQuoteRepository)) {
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			repo := backend(t)
+			for _, quote := range quotes {
+				assert.NoError(t, repo.CreateDollarQuote(context.Background(), quote, time.Second))
+			}
+			test(t, repo)
+		})
+	}
+}
+
+func TestCreateDollarQuote(t *testing.T) {
+	forEachBackend(t, nil, func(t *testing.T, repo DollarQuoteRepository) {
+		t.Run("should get DB_OPERATION_TIMEOUT", func(t *testing.T) {
+			err := repo.CreateDollarQuote(context.Background(), newQuote("USD", "BRL", "5.10", "5.11", 1000), 0)
+			assert.EqualError(t, err, TimeoutError)
+			assert.ErrorIs(t, err, ErrTimeout)
+
+			quotes, err := repo.ListDollarQuotes(context.Background(), q.HistoryFilter{Limit: 10}, time.Second)
+			assert.NoError(t, err)
+			assert.Empty(t, quotes, "a timed out write should store nothing")
+		})
+
+		t.Run("should tell caller cancellation from timeout", func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := repo.CreateDollarQuote(ctx, newQuote("USD", "BRL", "5.10", "5.11", 1000), time.Second)
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.NotErrorIs(t, err, ErrTimeout)
+		})
+	})
+}
+
+func TestCreateDollarQuotes(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, nil, func(t *testing.T, repo DollarQuoteRepository) {
+		t.Run("should store the whole batch", func(t *testing.T) {
+			err := repo.CreateDollarQuotes(ctx, []q.DollarQuote{
+				newQuote("USD", "BRL", "5.10", "5.11", 1000),
+				newQuote("USD", "BRL", "5.20", "5.21", 2000),
+				newQuote("EUR", "BRL", "6.10", "6.11", 2000),
+			}, time.Second)
+			assert.NoError(t, err)
+
+			quotes, err := repo.ListDollarQuotes(ctx, q.HistoryFilter{Limit: 10}, time.Second)
+			assert.NoError(t, err)
+			assert.Len(t, quotes, 3)
+		})
+
+		t.Run("should get DB_OPERATION_TIMEOUT", func(t *testing.T) {
+			err := repo.CreateDollarQuotes(ctx, []q.DollarQuote{newQuote("USD", "BRL", "5.30", "5.31", 3000)}, 0)
+			assert.EqualError(t, err, TimeoutError)
+
+			quotes, err := repo.ListDollarQuotes(ctx, q.HistoryFilter{From: time.Unix(3000, 0), Limit: 10}, time.Second)
+			assert.NoError(t, err)
+			assert.Empty(t, quotes, "a timed out batch should store nothing")
+		})
+	})
+}
+
+func TestListDollarQuotes(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, []q.DollarQuote{
+		newQuote("USD", "BRL", "5.10", "5.11", 1000),
+		newQuote("USD", "BRL", "5.20", "5.21", 2000),
+		newQuote("EUR", "BRL", "6.00", "6.01", 2500),
+		newQuote("USD", "BRL", "5.30", "5.31", 3000),
+	}, func(t *testing.T, repo DollarQuoteRepository) {
+
+		t.Run("should list newest first", func(t *testing.T) {
+			quotes, err := repo.ListDollarQuotes(ctx, q.HistoryFilter{Limit: 10}, time.Second)
+			assert.NoError(t, err)
+			assert.Len(t, quotes, 4)
+			assert.Equal(t, int64(3000), quotes[0].Timestamp)
+			assert.Equal(t, int64(1000), quotes[3].Timestamp)
+		})
+
+		t.Run("should filter by pair and time range", func(t *testing.T) {
+			quotes, err := repo.ListDollarQuotes(ctx, q.HistoryFilter{
+				Pair:  "usd-brl",
+				From:  time.Unix(1500, 0),
+				To:    time.Unix(3000, 0),
+				Limit: 10,
+			}, time.Second)
+			assert.NoError(t, err)
+			assert.Len(t, quotes, 2)
+			assert.Equal(t, "5.3", quotes[0].Bid.String())
+			assert.Equal(t, "5.2", quotes[1].Bid.String())
+		})
+
+		t.Run("should paginate", func(t *testing.T) {
+			quotes, err := repo.ListDollarQuotes(ctx, q.HistoryFilter{Limit: 2, Offset: 1}, time.Second)
+			assert.NoError(t, err)
+			assert.Len(t, quotes, 2)
+			assert.Equal(t, int64(2500), quotes[0].Timestamp)
+			assert.Equal(t, int64(2000), quotes[1].Timestamp)
+		})
+	})
+}
+
+func TestExportDollarQuotes(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, []q.DollarQuote{
+		newQuote("USD", "BRL", "5.30", "5.31", 3000),
+		newQuote("USD", "BRL", "5.10", "5.11", 1000),
+		newQuote("EUR", "BRL", "6.00", "6.01", 2500),
+		newQuote("USD", "BRL", "5.20", "5.21", 2000),
+	}, func(t *testing.T, repo DollarQuoteRepository) {
+		export := func(filter q.ExportFilter) ([]int64, error) {
+			var timestamps []int64
+			err := repo.ExportDollarQuotes(ctx, filter, func(quote q.DollarQuote) error {
+				timestamps = append(timestamps, quote.Timestamp)
+				return nil
+			})
+			return timestamps, err
+		}
+
+		t.Run("should export oldest first", func(t *testing.T) {
+			timestamps, err := export(q.ExportFilter{})
+			assert.NoError(t, err)
+			assert.Equal(t, []int64{1000, 2000, 2500, 3000}, timestamps)
+		})
+
+		t.Run("should filter by pair and time range", func(t *testing.T) {
+			timestamps, err := export(q.ExportFilter{Pair: "usd-brl", From: time.Unix(1500, 0), To: time.Unix(3000, 0)})
+			assert.NoError(t, err)
+			assert.Equal(t, []int64{2000, 3000}, timestamps)
+		})
+
+		t.Run("should stop at the first callback error", func(t *testing.T) {
+			stop := errors.New("stop")
+			calls := 0
+			err := repo.ExportDollarQuotes(ctx, q.ExportFilter{}, func(q.DollarQuote) error {
+				calls++
+				return stop
+			})
+			assert.ErrorIs(t, err, stop)
+			assert.Equal(t, 1, calls)
+		})
+
+		t.Run("should stop when the caller goes away", func(t *testing.T) {
+			cancelled, cancel := context.WithCancel(ctx)
+			cancel()
+			err := repo.ExportDollarQuotes(cancelled, q.ExportFilter{}, func(q.DollarQuote) error { return nil })
+			assert.ErrorIs(t, err, context.Canceled)
+		})
+
+		t.Run("should not lock writes out while exporting", func(t *testing.T) {
+			err := repo.ExportDollarQuotes(ctx, q.ExportFilter{}, func(quote q.DollarQuote) error {
+				if quote.Timestamp != 1000 {
+					return nil
+				}
+				return repo.CreateDollarQuotes(ctx, []q.DollarQuote{newQuote("USD", "BRL", "5.40", "5.41", 4000)}, time.Second)
+			})
+			assert.NoError(t, err)
+
+			timestamps, err := export(q.ExportFilter{From: time.Unix(4000, 0)})
+			assert.NoError(t, err)
+			assert.Equal(t, []int64{4000}, timestamps)
+		})
+	})
+}
+
+func TestFindLatestDollarQuote(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, []q.DollarQuote{
+		newQuote("USD", "BRL", "5.30", "5.31", 3000),
+		newQuote("USD", "BRL", "5.10", "5.11", 1000),
+		newQuote("EUR", "BRL", "6.00", "6.01", 4000),
+	}, func(t *testing.T, repo DollarQuoteRepository) {
+		t.Run("should find newest quote of pair", func(t *testing.T) {
+			quote, err := repo.FindLatestDollarQuote(ctx, "USD-BRL", time.Second)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(3000), quote.Timestamp)
+			assert.Equal(t, "5.3", quote.Bid.String())
+		})
+
+		t.Run("should get QUOTE_NOT_FOUND", func(t *testing.T) {
+			_, err := repo.FindLatestDollarQuote(ctx, "GBP-BRL", time.Second)
+			assert.EqualError(t, err, NotFoundError)
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	})
+}
+
+func TestAggregateDollarQuotes(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, []q.DollarQuote{
+		newQuote("USD", "BRL", "5.10", "5.12", 3600),
+		newQuote("USD", "BRL", "5.40", "5.44", 3700),
+		newQuote("USD", "BRL", "5.00", "5.06", 4000),
+		newQuote("USD", "BRL", "5.20", "5.22", 7300),
+		newQuote("EUR", "BRL", "6.00", "6.10", 3650),
+	}, func(t *testing.T, repo DollarQuoteRepository) {
+
+		t.Run("should build hourly candles", func(t *testing.T) {
+			candles, err := repo.AggregateDollarQuotes(ctx, q.CandleFilter{Pair: "USD-BRL", Interval: "1h", Limit: 10}, time.Second)
+			assert.NoError(t, err)
+			assert.Len(t, candles, 2)
+
+			assert.Equal(t, time.Unix(3600, 0).UTC(), candles[0].Start)
+			assert.Equal(t, "5.1", candles[0].Open.String())
+			assert.Equal(t, "5.4", candles[0].High.String())
+			assert.Equal(t, "5", candles[0].Low.String())
+			assert.Equal(t, "5", candles[0].Close.String())
+			assert.Equal(t, "0.04", candles[0].AvgSpread.String())
+			assert.Equal(t, 3, candles[0].Count)
+
+			assert.Equal(t, time.Unix(7200, 0).UTC(), candles[1].Start)
+			assert.Equal(t, "5.2", candles[1].Open.String())
+			assert.Equal(t, "5.2", candles[1].Close.String())
+			assert.Equal(t, 1, candles[1].Count)
+		})
+
+		t.Run("should respect time range", func(t *testing.T) {
+			candles, err := repo.AggregateDollarQuotes(ctx, q.CandleFilter{Pair: "USD-BRL", Interval: "1m", From: time.Unix(3650, 0), To: time.Unix(4000, 0), Limit: 10}, time.Second)
+			assert.NoError(t, err)
+			assert.Len(t, candles, 2)
+			assert.Equal(t, "5.4", candles[0].Open.String())
+			assert.Equal(t, "5", candles[1].Open.String())
+		})
+
+		t.Run("should keep the newest buckets over the limit", func(t *testing.T) {
+			candles, err := repo.AggregateDollarQuotes(ctx, q.CandleFilter{Pair: "USD-BRL", Interval: "1m", Limit: 2}, time.Second)
+			assert.NoError(t, err)
+			assert.Len(t, candles, 2)
+			assert.Equal(t, time.Unix(3960, 0).UTC(), candles[0].Start)
+			assert.Equal(t, "5", candles[0].Open.String())
+			assert.Equal(t, time.Unix(7260, 0).UTC(), candles[1].Start)
+			assert.Equal(t, "5.2", candles[1].Open.String())
+		})
+
+		t.Run("should reject unknown interval", func(t *testing.T) {
+			_, err := repo.AggregateDollarQuotes(ctx, q.CandleFilter{Pair: "USD-BRL", Interval: "2w", Limit: 10}, time.Second)
+			assert.EqualError(t, err, q.UnsupportedIntervalError)
+		})
+	})
+}
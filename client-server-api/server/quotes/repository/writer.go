@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+)
+
+type (
+	WriterConfig struct {
+		// QueueSize bounds the quotes waiting to be written; when it is full new quotes
+		// are dropped instead of blocking the caller.
+		QueueSize int
+		// A batch is written once it has BatchSize quotes or FlushInterval has passed.
+		BatchSize     int
+		FlushInterval time.Duration
+		// BatchTimeout is the budget of each insert transaction.
+		BatchTimeout time.Duration
+		// A failed batch is retried MaxRetries times, waiting RetryBackoff and then twice
+		// as long before every further attempt.
+		MaxRetries   int
+		RetryBackoff time.Duration
+	}
+
+	WriterStats struct {
+		Queued  int64 `json:"queued"`
+		Written int64 `json:"written"`
+		Dropped int64 `json:"dropped"`
+		Failed  int64 `json:"failed"`
+		Retries int64 `json:"retries"`
+		Pending int   `json:"pending"`
+	}
+
+	// Writer is a write-behind DollarQuoteRepository: CreateDollarQuote only enqueues the
+	// quote, and a background loop stores the queue in batches. Reads go straight to the
+	// wrapped repository, so they do not see quotes still in the queue.
+	Writer struct {
+		DollarQuoteRepository
+		cfg   WriterConfig
+		queue chan q.DollarQuote
+		done  chan struct{}
+		// ctx is cancelled when Close gives up waiting, aborting the pending writes
+		ctx    context.Context
+		cancel context.CancelFunc
+
+		mu     sync.RWMutex
+		closed bool
+
+		queued, written, dropped, failed, retries atomic.Int64
+	}
+)
+
+func NewWriter(repo DollarQuoteRepository, cfg WriterConfig) *Writer {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1024
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.BatchTimeout <= 0 {
+		cfg.BatchTimeout = time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Writer{
+		DollarQuoteRepository: repo,
+		cfg:                   cfg,
+		queue:                 make(chan q.DollarQuote, cfg.QueueSize),
+		done:                  make(chan struct{}),
+		ctx:                   ctx,
+		cancel:                cancel,
+	}
+	go w.run()
+
+	return w
+}
+
+// CreateDollarQuote enqueues quote and returns at once; the timeout is not used. A quote
+// that does not fit in the queue, or arrives after Close, is dropped and counted.
+func (w *Writer) CreateDollarQuote(_ context.Context, quote q.DollarQuote, _ time.Duration) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		w.drop(quote, "writer closed")
+		return nil
+	}
+
+	select {
+	case w.queue <- quote:
+		w.queued.Add(1)
+	default:
+		w.drop(quote, "queue full")
+	}
+	return nil
+}
+
+// CreateDollarQuotes enqueues every quote in quotes, see CreateDollarQuote.
+func (w *Writer) CreateDollarQuotes(c context.Context, quotes []q.DollarQuote, t time.Duration) error {
+	for _, quote := range quotes {
+		w.CreateDollarQuote(c, quote, t)
+	}
+	return nil
+}
+
+// Close stops accepting quotes and waits until the queue is written. When ctx is done
+// first, the write in progress is aborted and the quotes left are counted as failed;
+// Close still waits for the loop to stop, so the wrapped repository is no longer used
+// once it returns.
+func (w *Writer) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		w.cancel()
+		return nil
+	case <-ctx.Done():
+	}
+
+	pending := len(w.queue)
+	w.cancel()
+	<-w.done
+	log.Printf("quote writer closed with %d quotes pending, abandoned", pending)
+	return ctx.Err()
+}
+
+func (w *Writer) Stats() WriterStats {
+	return WriterStats{
+		Queued:  w.queued.Load(),
+		Written: w.written.Load(),
+		Dropped: w.dropped.Load(),
+		Failed:  w.failed.Load(),
+		Retries: w.retries.Load(),
+		Pending: len(w.queue),
+	}
+}
+
+func (w *Writer) drop(quote q.DollarQuote, reason string) {
+	w.dropped.Add(1)
+	log.Printf("dropped %s quote from %d: %s", quote.Pair(), quote.Timestamp, reason)
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]q.DollarQuote, 0, w.cfg.BatchSize)
+	for {
+		select {
+		case quote, ok := <-w.queue:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			batch = append(batch, quote)
+			if len(batch) >= w.cfg.BatchSize {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			w.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (w *Writer) flush(batch []q.DollarQuote) {
+	if len(batch) == 0 {
+		return
+	}
+
+	backoff := w.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := w.DollarQuoteRepository.CreateDollarQuotes(w.ctx, batch, w.cfg.BatchTimeout)
+		if err == nil {
+			w.written.Add(int64(len(batch)))
+			return
+		}
+		switch {
+		case w.ctx.Err() != nil:
+			// Close gave up waiting, the quotes are reported as pending there
+			w.failed.Add(int64(len(batch)))
+			return
+		case attempt >= w.cfg.MaxRetries:
+			w.failed.Add(int64(len(batch)))
+			log.Printf("failed to write %d quotes after %d attempts: %v", len(batch), attempt+1, err)
+			return
+		}
+
+		w.retries.Add(1)
+		log.Printf("writing %d quotes failed, retrying in %s: %v", len(batch), backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-w.ctx.Done():
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flaky fails the first failures batch inserts, then stores batches in memory. Inserts
+// wait for release when it is set, or until their context is done.
+type flaky struct {
+	*Memory
+	failures atomic.Int64
+	batches  atomic.Int64
+	running  atomic.Int64
+	release  chan struct{}
+}
+
+func (f *flaky) CreateDollarQuotes(c context.Context, quotes []q.DollarQuote, t time.Duration) error {
+	f.running.Add(1)
+	defer f.running.Add(-1)
+	if f.release != nil {
+		select {
+		case <-f.release:
+		case <-c.Done():
+			return c.Err()
+		}
+	}
+	f.batches.Add(1)
+	if f.failures.Add(-1) >= 0 {
+		return errors.New(TimeoutError)
+	}
+	return f.Memory.CreateDollarQuotes(c, quotes, t)
+}
+
+func stored(t *testing.T, repo DollarQuoteRepository) int {
+	quotes, err := repo.ListDollarQuotes(context.Background(), q.HistoryFilter{Limit: q.MaxHistoryLimit}, time.Second)
+	assert.NoError(t, err)
+	return len(quotes)
+}
+
+func TestWriter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should write full batches in the background", func(t *testing.T) {
+		repo := &flaky{Memory: NewMemory()}
+		writer := NewWriter(repo, WriterConfig{BatchSize: 2, FlushInterval: time.Hour})
+
+		for i := int64(1); i <= 4; i++ {
+			assert.NoError(t, writer.CreateDollarQuote(ctx, newQuote("USD", "BRL", "5.10", "5.11", i), 0))
+		}
+
+		assert.Eventually(t, func() bool { return stored(t, writer) == 4 }, time.Second, time.Millisecond)
+		assert.Equal(t, int64(2), repo.batches.Load())
+		assert.NoError(t, writer.Close(ctx))
+	})
+
+	t.Run("should write partial batch after flush interval", func(t *testing.T) {
+		writer := NewWriter(NewMemory(), WriterConfig{BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+
+		writer.CreateDollarQuote(ctx, newQuote("USD", "BRL", "5.10", "5.11", 1), 0)
+
+		assert.Eventually(t, func() bool { return stored(t, writer) == 1 }, time.Second, time.Millisecond)
+		assert.NoError(t, writer.Close(ctx))
+	})
+
+	t.Run("should flush queue on close", func(t *testing.T) {
+		writer := NewWriter(NewMemory(), WriterConfig{BatchSize: 100, FlushInterval: time.Hour})
+
+		for i := int64(1); i <= 3; i++ {
+			writer.CreateDollarQuote(ctx, newQuote("USD", "BRL", "5.10", "5.11", i), 0)
+		}
+		assert.NoError(t, writer.Close(ctx))
+
+		assert.Equal(t, 3, stored(t, writer))
+		assert.Equal(t, WriterStats{Queued: 3, Written: 3}, writer.Stats())
+	})
+
+	t.Run("should retry failed batch", func(t *testing.T) {
+		repo := &flaky{Memory: NewMemory()}
+		repo.failures.Store(2)
+		writer := NewWriter(repo, WriterConfig{MaxRetries: 2, RetryBackoff: time.Millisecond})
+
+		writer.CreateDollarQuote(ctx, newQuote("USD", "BRL", "5.10", "5.11", 1), 0)
+		assert.NoError(t, writer.Close(ctx))
+
+		assert.Equal(t, 1, stored(t, writer))
+		assert.Equal(t, WriterStats{Queued: 1, Written: 1, Retries: 2}, writer.Stats())
+	})
+
+	t.Run("should count batch failing every attempt", func(t *testing.T) {
+		repo := &flaky{Memory: NewMemory()}
+		repo.failures.Store(10)
+		writer := NewWriter(repo, WriterConfig{MaxRetries: 1, RetryBackoff: time.Millisecond})
+
+		writer.CreateDollarQuote(ctx, newQuote("USD", "BRL", "5.10", "5.11", 1), 0)
+		writer.CreateDollarQuote(ctx, newQuote("USD", "BRL", "5.20", "5.21", 2), 0)
+		assert.NoError(t, writer.Close(ctx))
+
+		assert.Equal(t, 0, stored(t, writer))
+		assert.Equal(t, WriterStats{Queued: 2, Failed: 2, Retries: 1}, writer.Stats())
+	})
+
+	t.Run("should drop quotes when queue is full", func(t *testing.T) {
+		repo := &flaky{Memory: NewMemory(), release: make(chan struct{})}
+		writer := NewWriter(repo, WriterConfig{QueueSize: 1, BatchSize: 1})
+
+		// the first quote is taken by the blocked insert, the second fills the queue
+		writer.CreateDollarQuote(ctx, newQuote("USD", "BRL", "5.10", "5.11", 1), 0)
+		assert.Eventually(t, func() bool { return writer.Stats().Pending == 0 }, time.Second, time.Millisecond)
+		writer.CreateDollarQuote(ctx, newQuote("USD", "BRL", "5.20", "5.21", 2), 0)
+		writer.CreateDollarQuote(ctx, newQuote("USD", "BRL", "5.30", "5.31", 3), 0)
+
+		close(repo.release)
+		assert.NoError(t, writer.Close(ctx))
+
+		assert.Equal(t, 2, stored(t, writer))
+		assert.Equal(t, WriterStats{Queued: 2, Written: 2, Dropped: 1}, writer.Stats())
+	})
+
+	t.Run("should drop quotes after close", func(t *testing.T) {
+		writer := NewWriter(NewMemory(), WriterConfig{})
+		assert.NoError(t, writer.Close(ctx))
+
+		assert.NoError(t, writer.CreateDollarQuote(ctx, newQuote("USD", "BRL", "5.10", "5.11", 1), 0))
+		assert.Equal(t, int64(1), writer.Stats().Dropped)
+	})
+
+	t.Run("should abandon the queue when close context ends", func(t *testing.T) {
+		repo := &flaky{Memory: NewMemory(), release: make(chan struct{})}
+		defer close(repo.release)
+		writer := NewWriter(repo, WriterConfig{BatchSize: 1, MaxRetries: 3, RetryBackoff: time.Hour})
+
+		writer.CreateDollarQuote(ctx, newQuote("USD", "BRL", "5.10", "5.11", 1), 0)
+		writer.CreateDollarQuote(ctx, newQuote("USD", "BRL", "5.20", "5.21", 2), 0)
+		assert.Eventually(t, func() bool { return repo.running.Load() == 1 }, time.Second, time.Millisecond)
+
+		closeCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, writer.Close(closeCtx), context.DeadlineExceeded)
+
+		// the repository can be closed as soon as Close returns
+		assert.Equal(t, int64(0), repo.running.Load())
+		assert.Equal(t, int64(2), writer.Stats().Failed)
+		assert.Equal(t, 0, stored(t, repo.Memory))
+	})
+}
@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"sync"
+	"time"
+
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+)
+
+type (
+	cacheEntry struct {
+		quote     q.DollarQuote
+		fetchedAt time.Time
+	}
+
+	// quoteCache keeps the last fetched quote of each pair.
+	quoteCache struct {
+		mu      sync.RWMutex
+		entries map[string]cacheEntry
+	}
+)
+
+func newQuoteCache() *quoteCache {
+	return &quoteCache{entries: map[string]cacheEntry{}}
+}
+
+func (c *quoteCache) get(pair string) (cacheEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[pair]
+	return entry, ok
+}
+
+func (c *quoteCache) set(pair string, quote q.DollarQuote, fetchedAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if current, ok := c.entries[pair]; ok && current.fetchedAt.After(fetchedAt) {
+		return
+	}
+	c.entries[pair] = cacheEntry{quote: quote, fetchedAt: fetchedAt}
+}
@@ -2,99 +2,407 @@ package usecase
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"time"
 
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/provider"
 	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/repository"
 
 	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
-	TimeoutError = "EXTERNAL_API_CALL_TIMEOUT"
+	TimeoutError  = "EXTERNAL_API_CALL_TIMEOUT"
+	UpstreamError = "EXTERNAL_API_CALL_FAILED"
+
+	// FallbackNone fails the request when the upstream times out.
+	FallbackNone = "none"
+	// FallbackLastPersisted answers an upstream timeout with the newest stored quote.
+	FallbackLastPersisted = "last-persisted"
+)
+
+var (
+	// ErrTimeout is returned when the provider did not answer within ApiCallTimeoutMs.
+	ErrTimeout = errors.New(TimeoutError)
+	// ErrUpstream wraps any other provider failure; a malformed payload also matches
+	// quotes.ErrInvalidQuote.
+	ErrUpstream = errors.New(UpstreamError)
 )
 
 type (
 	GetterDollarQuote interface {
-		GetDollarQuote() (*string, error)
+		GetDollarQuote(ctx context.Context) (*string, error)
+		GetQuote(ctx context.Context, pair string) (*QuoteResult, error)
+	}
+
+	GetterQuoteHistory interface {
+		GetQuoteHistory(ctx context.Context, filter q.HistoryFilter) ([]q.DollarQuote, error)
+	}
+
+	GetterQuoteCandles interface {
+		GetQuoteCandles(ctx context.Context, filter q.CandleFilter) ([]q.Candle, error)
+	}
+
+	ConverterQuote interface {
+		Convert(ctx context.Context, from, to string, amount decimal.Decimal, side string) (*Conversion, error)
+	}
+
+	QuoteUsecase interface {
+		GetterDollarQuote
+		GetterQuoteHistory
+		GetterQuoteCandles
+		ConverterQuote
 	}
 
 	Config struct {
+		// ApiURL is the awesomeapi base URL used by New; NewWithProvider ignores it.
+		ApiURL               string  `env:"API_URL" envDefault:"https://economia.awesomeapi.com.br/json/"`
 		ApiCallTimeoutMs     int     `env:"API_CALL_TIMEOUT_MS" envDefault:"200"`
 		DbOperationTimeoutMs float32 `env:"DB_OPERATION_TIMEOUT_MS" envDefault:"10"`
+		DbQueryTimeoutMs     int     `env:"DB_QUERY_TIMEOUT_MS" envDefault:"1000"`
+		// A cached quote younger than CacheTTLMs is served without calling the upstream.
+		// Up to CacheStaleMs after that it is still served, while a refresh runs in the
+		// background. A zero TTL disables the cache.
+		CacheTTLMs   int `env:"QUOTE_CACHE_TTL_MS" envDefault:"0"`
+		CacheStaleMs int `env:"QUOTE_CACHE_STALE_MS" envDefault:"0"`
+		// FallbackPolicy is FallbackNone or FallbackLastPersisted. A persisted quote older
+		// than FallbackMaxAgeMs is not served; zero accepts any age.
+		FallbackPolicy   string `env:"QUOTE_FALLBACK" envDefault:"none"`
+		FallbackMaxAgeMs int    `env:"QUOTE_FALLBACK_MAX_AGE_MS" envDefault:"0"`
+	}
+
+	// QuoteResult is a quote together with where it came from: Cached is set when no
+	// upstream call was made for this request, and Age is the time since it was fetched.
+	// Stale is set when the upstream timed out and the quote was read back from the
+	// repository; Age is then measured from the quote timestamp.
+	QuoteResult struct {
+		Quote  q.DollarQuote
+		Cached bool
+		Stale  bool
+		Age    time.Duration
+	}
+
+	// Conversion is Amount of From expressed in To, using the Side price of the quotes of
+	// Pairs. Rate is the price of one From in To. Cached and Stale are set when every, or
+	// any, quote was served that way, and Timestamp is that of the oldest one.
+	Conversion struct {
+		From      string
+		To        string
+		Side      string
+		Amount    decimal.Decimal
+		Rate      decimal.Decimal
+		Result    decimal.Decimal
+		Pairs     []string
+		Cached    bool
+		Stale     bool
+		Timestamp int64
 	}
 
+	// Usecase serves every call within the context it is given; ctx, the one from New,
+	// only bounds the background cache refreshes.
 	Usecase struct {
-		ctx  context.Context
-		repo repository.CreaterDollarQuote
-		cfg  Config
+		ctx      context.Context
+		repo     repository.DollarQuoteRepository
+		provider provider.QuoteProvider
+		cfg      Config
+		cache    *quoteCache
+		inflight singleflight.Group
+		now      func() time.Time
 	}
 )
 
-func New(ctx context.Context, repo repository.CreaterDollarQuote, cfg Config) *Usecase {
+// New returns a usecase fetching quotes from awesomeapi at cfg.ApiURL.
+func New(ctx context.Context, repo repository.DollarQuoteRepository, cfg Config) *Usecase {
+	return NewWithProvider(ctx, repo, provider.NewAwesomeAPI(cfg.ApiURL, nil), cfg)
+}
+
+func NewWithProvider(ctx context.Context, repo repository.DollarQuoteRepository, p provider.QuoteProvider, cfg Config) *Usecase {
+	if cfg.FallbackPolicy != FallbackNone && cfg.FallbackPolicy != FallbackLastPersisted {
+		if cfg.FallbackPolicy != "" {
+			log.Printf("unknown fallback policy %q, using %q", cfg.FallbackPolicy, FallbackNone)
+		}
+		cfg.FallbackPolicy = FallbackNone
+	}
+
 	return &Usecase{
-		ctx:  ctx,
-		repo: repo,
-		cfg:  cfg,
+		ctx:      ctx,
+		repo:     repo,
+		provider: p,
+		cfg:      cfg,
+		cache:    newQuoteCache(),
+		now:      time.Now,
 	}
 }
 
-func (u *Usecase) GetDollarQuote() (*string, error) {
-	res, err := apiCall(u.ctx, time.Duration(u.cfg.ApiCallTimeoutMs)*time.Millisecond)
+func (u *Usecase) GetDollarQuote(ctx context.Context) (*string, error) {
+	result, err := u.GetQuote(ctx, q.DefaultPair)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Println("res", res.Status)
-	defer res.Body.Close()
+	bid := result.Quote.Bid.String()
+	return &bid, nil
+}
 
-	body, err := io.ReadAll(res.Body)
+func (u *Usecase) GetQuote(ctx context.Context, pair string) (*QuoteResult, error) {
+	pair = q.NormalizePair(pair)
+	if !q.IsSupportedPair(pair) {
+		return nil, q.ErrUnsupportedPair
+	}
+
+	ttl := time.Duration(u.cfg.CacheTTLMs) * time.Millisecond
+	stale := time.Duration(u.cfg.CacheStaleMs) * time.Millisecond
+	if ttl > 0 {
+		if entry, ok := u.cache.get(pair); ok {
+			age := u.now().Sub(entry.fetchedAt)
+			switch {
+			case age < ttl:
+				return &QuoteResult{Quote: entry.quote, Cached: true, Age: age}, nil
+			case age < ttl+stale:
+				go u.refresh(pair)
+				return &QuoteResult{Quote: entry.quote, Cached: true, Age: age}, nil
+			}
+		}
+	}
+
+	entry, err := u.fetch(ctx, pair)
 	if err != nil {
+		if errors.Is(err, ErrTimeout) && u.cfg.FallbackPolicy == FallbackLastPersisted {
+			return u.fallback(ctx, pair, err)
+		}
+		return nil, err
+	}
+
+	return &QuoteResult{Quote: entry.quote, Age: u.now().Sub(entry.fetchedAt)}, nil
+}
+
+// PollQuote fetches and stores a fresh quote of pair, bypassing the cache, which it
+// refreshes. It has no fallback: a failure is reported as is.
+func (u *Usecase) PollQuote(ctx context.Context, pair string) (q.DollarQuote, error) {
+	pair = q.NormalizePair(pair)
+	if !q.IsSupportedPair(pair) {
+		return q.DollarQuote{}, q.ErrUnsupportedPair
+	}
+
+	entry, err := u.fetch(ctx, pair)
+	return entry.quote, err
+}
+
+// Convert converts amount of from into to with the latest quotes, as GetQuote serves
+// them, along quotes.ConversionPath. The rate is kept as a fraction until the end, so a
+// cross rate only rounds once: Result to quotes.ConversionPrecision decimal places and
+// Rate to quotes.RatePrecision. An empty side is the bid.
+func (u *Usecase) Convert(ctx context.Context, from, to string, amount decimal.Decimal, side string) (*Conversion, error) {
+	if side == "" {
+		side = q.SideBid
+	}
+	if _, err := (q.DollarQuote{}).Price(side); err != nil {
 		return nil, err
 	}
+	if amount.IsNegative() {
+		return nil, fmt.Errorf("%w: amount must not be negative", q.ErrInvalidConversion)
+	}
 
-	var dollarQuotes []q.DollarQuote
-	err = json.Unmarshal(body, &dollarQuotes)
+	path, err := q.ConversionPath(from, to)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(dollarQuotes) == 0 {
-		return nil, fmt.Errorf("no quotes found")
+	conversion := &Conversion{
+		From:   q.NormalizeCurrency(from),
+		To:     q.NormalizeCurrency(to),
+		Side:   side,
+		Amount: amount,
+		Pairs:  []string{},
+		Cached: len(path) > 0,
 	}
+	numerator, denominator := decimal.NewFromInt(1), decimal.NewFromInt(1)
+	for _, leg := range path {
+		result, err := u.GetQuote(ctx, leg.Pair)
+		if err != nil {
+			return nil, err
+		}
+
+		price, _ := result.Quote.Price(side)
+		if !price.IsPositive() {
+			return nil, fmt.Errorf("%w: %s of %s is %s", q.ErrInvalidQuote, side, leg.Pair, price)
+		}
+		if leg.Inverse {
+			denominator = denominator.Mul(price)
+		} else {
+			numerator = numerator.Mul(price)
+		}
+
+		conversion.Pairs = append(conversion.Pairs, leg.Pair)
+		conversion.Cached = conversion.Cached && result.Cached
+		conversion.Stale = conversion.Stale || result.Stale
+		if conversion.Timestamp == 0 || result.Quote.Timestamp < conversion.Timestamp {
+			conversion.Timestamp = result.Quote.Timestamp
+		}
+	}
+
+	conversion.Rate = numerator.DivRound(denominator, q.RatePrecision)
+	conversion.Result = amount.Mul(numerator).DivRound(denominator, q.ConversionPrecision)
+	return conversion, nil
+}
 
-	err = u.repo.CreateDollarQuote(u.ctx, dollarQuotes[0], time.Duration(u.cfg.DbOperationTimeoutMs)*time.Millisecond)
+// fallback serves the newest persisted quote of pair after the upstream failed with
+// cause. When there is none, or it is too old, cause is returned unchanged.
+func (u *Usecase) fallback(ctx context.Context, pair string, cause error) (*QuoteResult, error) {
+	t := time.Duration(u.cfg.DbQueryTimeoutMs) * time.Millisecond
+	quote, err := u.repo.FindLatestDollarQuote(ctx, pair, t)
 	if err != nil {
-		return nil, err
+		logBudget(err, "DB_QUERY_TIMEOUT_MS", t, "reading fallback quote of "+pair)
+		log.Printf("no fallback quote for %s: %v", pair, err)
+		return nil, cause
 	}
 
-	result := dollarQuotes[0].Bid
+	age := u.now().Sub(quote.Time())
+	if maxAge := time.Duration(u.cfg.FallbackMaxAgeMs) * time.Millisecond; maxAge > 0 && age > maxAge {
+		log.Printf("fallback quote for %s is too old: %s", pair, age)
+		return nil, cause
+	}
 
-	return &result, nil
+	log.Printf("%s for %s, serving persisted quote from %s", cause, pair, quote.Time().Format(time.RFC3339))
+	return &QuoteResult{Quote: *quote, Stale: true, Age: age}, nil
 }
 
-func apiCall(c context.Context, t time.Duration) (*http.Response, error) {
-	ctx, cancel := context.WithTimeout(c, t)
-	defer cancel()
+// fetch calls the upstream and stores the quote. Concurrent fetches of the same pair
+// share a single upstream call, made within the context of the caller that started it;
+// when that caller goes away the others start a new one.
+func (u *Usecase) fetch(ctx context.Context, pair string) (cacheEntry, error) {
+	for {
+		ch := u.inflight.DoChan(pair, func() (any, error) {
+			return u.fetchAndStore(ctx, pair)
+		})
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://economia.awesomeapi.com.br/json/USD-BRL", nil)
+		select {
+		case <-ctx.Done():
+			return cacheEntry{}, ctx.Err()
+		case res := <-ch:
+			if res.Err != nil {
+				if res.Shared && errors.Is(res.Err, context.Canceled) && ctx.Err() == nil {
+					continue
+				}
+				return cacheEntry{}, res.Err
+			}
+			return res.Val.(cacheEntry), nil
+		}
+	}
+}
+
+func (u *Usecase) refresh(pair string) {
+	if _, err := u.fetch(u.ctx, pair); err != nil {
+		log.Printf("background refresh of %s failed: %v", pair, err)
+	}
+}
+
+// fetchAndStore calls the provider and stores the quote, unless it is a reference rate.
+func (u *Usecase) fetchAndStore(ctx context.Context, pair string) (cacheEntry, error) {
+	quote, err := u.apiCall(ctx, pair, time.Duration(u.cfg.ApiCallTimeoutMs)*time.Millisecond)
 	if err != nil {
-		return nil, err
+		return cacheEntry{}, err
+	}
+
+	if quote.Reference {
+		log.Printf("not storing %s quote from %s: %s", pair, quote.CreateDate, quote.Name)
+	} else {
+		t := time.Duration(u.cfg.DbOperationTimeoutMs) * time.Millisecond
+		err = u.repo.CreateDollarQuote(ctx, quote, t)
+		if err != nil {
+			logBudget(err, "DB_OPERATION_TIMEOUT_MS", t, "storing quote of "+pair)
+			return cacheEntry{}, err
+		}
+	}
+
+	entry := cacheEntry{quote: quote, fetchedAt: u.now()}
+	u.cache.set(pair, entry.quote, entry.fetchedAt)
+
+	return entry, nil
+}
+
+func (u *Usecase) GetQuoteHistory(ctx context.Context, filter q.HistoryFilter) ([]q.DollarQuote, error) {
+	if filter.Pair != "" {
+		filter.Pair = q.NormalizePair(filter.Pair)
+		if !q.IsSupportedPair(filter.Pair) {
+			return nil, q.ErrUnsupportedPair
+		}
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
 	switch {
+	case filter.Limit <= 0:
+		filter.Limit = q.DefaultHistoryLimit
+	case filter.Limit > q.MaxHistoryLimit:
+		filter.Limit = q.MaxHistoryLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	t := time.Duration(u.cfg.DbQueryTimeoutMs) * time.Millisecond
+	quotes, err := u.repo.ListDollarQuotes(ctx, filter, t)
+	logBudget(err, "DB_QUERY_TIMEOUT_MS", t, "listing quote history")
+	return quotes, err
+}
+
+func (u *Usecase) GetQuoteCandles(ctx context.Context, filter q.CandleFilter) ([]q.Candle, error) {
+	if filter.Pair == "" {
+		filter.Pair = q.DefaultPair
+	}
+	filter.Pair = q.NormalizePair(filter.Pair)
+	if !q.IsSupportedPair(filter.Pair) {
+		return nil, q.ErrUnsupportedPair
+	}
+
+	if filter.Interval == "" {
+		filter.Interval = q.DefaultCandleInterval
+	}
+	if _, ok := q.CandleIntervals[filter.Interval]; !ok {
+		return nil, q.ErrUnsupportedInterval
+	}
+
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = q.DefaultHistoryLimit
+	case filter.Limit > q.MaxHistoryLimit:
+		filter.Limit = q.MaxHistoryLimit
+	}
+
+	t := time.Duration(u.cfg.DbQueryTimeoutMs) * time.Millisecond
+	candles, err := u.repo.AggregateDollarQuotes(ctx, filter, t)
+	logBudget(err, "DB_QUERY_TIMEOUT_MS", t, "aggregating quote candles")
+	return candles, err
+}
+
+// apiCall asks the provider for the quote of pair; the whole exchange has to fit in t.
+// When c ends first its error is returned as is, so a caller going away is not
+// reported as a timeout.
+func (u *Usecase) apiCall(c context.Context, pair string, t time.Duration) (q.DollarQuote, error) {
+	ctx, cancel := context.WithTimeout(c, t)
+	defer cancel()
+
+	quote, err := u.provider.FetchQuote(ctx, pair)
+	switch {
+	case err == nil:
+		return quote, nil
+	case c.Err() != nil:
+		return q.DollarQuote{}, c.Err()
 	case ctx.Err() != nil:
-		return nil, errors.New(TimeoutError)
-	case err != nil:
-		return nil, err
+		logBudget(ErrTimeout, "API_CALL_TIMEOUT_MS", t, "fetching quote of "+pair)
+		return q.DollarQuote{}, ErrTimeout
+	default:
+		return q.DollarQuote{}, fmt.Errorf("%w: %w", ErrUpstream, err)
 	}
+}
 
-	return resp, nil
+// logBudget logs err when it is one of the timeouts, naming the budget that was exceeded.
+func logBudget(err error, budget string, t time.Duration, operation string) {
+	if errors.Is(err, ErrTimeout) || errors.Is(err, repository.ErrTimeout) {
+		log.Printf("%s: %s exceeded its %s budget of %s", err, operation, budget, t)
+	}
 }
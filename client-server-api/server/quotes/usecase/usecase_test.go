@@ -1,58 +1,559 @@
 package usecase
 
 import (
+	"bytes"
 	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/provider"
 	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/repository"
 
 	mydb "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/database"
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
 
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestUsecase(t *testing.T) {
+	// newRepo stores in a fresh SQLite file, taking delay before each write or query.
+	newRepo := func(t *testing.T, delay time.Duration) repository.DollarQuoteRepository {
+		db, err := mydb.New(mydb.Config{File: filepath.Join(t.TempDir(), "sqlite.s3db"), RunMigration: true})
+		assert.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		return slowRepository{DollarQuoteRepository: repository.New(db.GetConnection()), delay: delay}
+	}
+
 	t.Run("should get dollar quote", func(t *testing.T) {
-		ctx := context.Background()
-		db, err := mydb.New(ctx, mydb.Config{File: "sqlite.s3db", RunMigration: true})
+		upstream, calls := newUpstream(t, nil)
+		usecase := New(context.Background(), newRepo(t, 0), Config{ApiURL: upstream.URL + "/json/", ApiCallTimeoutMs: 2000, DbOperationTimeoutMs: 1000})
+
+		dollarQuote, err := usecase.GetDollarQuote(context.Background())
 		assert.NoError(t, err)
+		assert.Equal(t, "5.35", *dollarQuote)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("should get EXTERNAL_API_CALL_TIMEOUT", func(t *testing.T) {
+		release := make(chan struct{})
+		upstream, _ := newUpstream(t, release)
+		t.Cleanup(func() { close(release) })
+		logs := captureLog(t)
+		usecase := New(context.Background(), newRepo(t, 0), Config{ApiURL: upstream.URL + "/json/", ApiCallTimeoutMs: 20, DbOperationTimeoutMs: 1000})
 
-		repo := repository.New(ctx, db.GetConnection())
-		usecase := New(ctx, repo, Config{ApiCallTimeoutMs: 2000, DbOperationTimeoutMs: 10})
+		_, err := usecase.GetDollarQuote(context.Background())
+		assert.ErrorIs(t, err, ErrTimeout)
+		assert.Equal(t, TimeoutError, err.Error())
+		assert.Contains(t, logs.String(), "fetching quote of USD-BRL exceeded its API_CALL_TIMEOUT_MS budget of 20ms")
+	})
+
+	t.Run("should get DB_OPERATION_TIMEOUT", func(t *testing.T) {
+		upstream, _ := newUpstream(t, nil)
+		logs := captureLog(t)
+		usecase := New(context.Background(), newRepo(t, time.Second), Config{ApiURL: upstream.URL + "/json/", ApiCallTimeoutMs: 2000, DbOperationTimeoutMs: 10})
+
+		_, err := usecase.GetDollarQuote(context.Background())
+		assert.ErrorIs(t, err, repository.ErrTimeout)
+		assert.Equal(t, repository.TimeoutError, err.Error())
+		assert.Contains(t, logs.String(), "storing quote of USD-BRL exceeded its DB_OPERATION_TIMEOUT_MS budget of 10ms")
+	})
+
+	t.Run("should get DB_OPERATION_TIMEOUT from a slow query", func(t *testing.T) {
+		logs := captureLog(t)
+		usecase := New(context.Background(), newRepo(t, time.Second), Config{DbQueryTimeoutMs: 10})
+
+		_, err := usecase.GetQuoteHistory(context.Background(), q.HistoryFilter{Pair: q.DefaultPair})
+		assert.ErrorIs(t, err, repository.ErrTimeout)
+		assert.Contains(t, logs.String(), "listing quote history exceeded its DB_QUERY_TIMEOUT_MS budget of 10ms")
+
+		_, err = usecase.GetQuoteCandles(context.Background(), q.CandleFilter{})
+		assert.ErrorIs(t, err, repository.ErrTimeout)
+		assert.Contains(t, logs.String(), "aggregating quote candles exceeded its DB_QUERY_TIMEOUT_MS budget of 10ms")
+	})
+}
+
+// slowRepository takes delay before every write and query, answering ErrTimeout when
+// that does not fit in the budget of the operation, as a busy database does.
+type slowRepository struct {
+	repository.DollarQuoteRepository
+	delay time.Duration
+}
 
-		dollarQuote, err := usecase.GetDollarQuote()
-		if err != nil {
-			t.Errorf("getDollarQuote() error = %v, want nil", err)
+func (r slowRepository) wait(c context.Context, t time.Duration) error {
+	ctx, cancel := context.WithTimeout(c, t)
+	defer cancel()
+
+	select {
+	case <-time.After(r.delay):
+		return nil
+	case <-ctx.Done():
+		if err := c.Err(); err != nil {
+			return err
 		}
+		return repository.ErrTimeout
+	}
+}
+
+func (r slowRepository) CreateDollarQuote(c context.Context, quote q.DollarQuote, t time.Duration) error {
+	if err := r.wait(c, t); err != nil {
+		return err
+	}
+	return r.DollarQuoteRepository.CreateDollarQuote(c, quote, t)
+}
+
+func (r slowRepository) ListDollarQuotes(c context.Context, filter q.HistoryFilter, t time.Duration) ([]q.DollarQuote, error) {
+	if err := r.wait(c, t); err != nil {
+		return nil, err
+	}
+	return r.DollarQuoteRepository.ListDollarQuotes(c, filter, t)
+}
+
+func (r slowRepository) AggregateDollarQuotes(c context.Context, filter q.CandleFilter, t time.Duration) ([]q.Candle, error) {
+	if err := r.wait(c, t); err != nil {
+		return nil, err
+	}
+	return r.DollarQuoteRepository.AggregateDollarQuotes(c, filter, t)
+}
+
+// captureLog collects what the standard logger writes until the test ends.
+func captureLog(t *testing.T) *syncBuffer {
+	logs := &syncBuffer{}
+	log.SetOutput(logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return logs
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
 
-		assert.NotEmpty(t, dollarQuote)
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+const upstreamPayload = `[{"code":"USD","codein":"BRL","name":"Dólar Americano/Real Brasileiro","high":"5.40","low":"5.30","varBid":"0.01","pctChange":"0.2","bid":"5.35","ask":"5.36","timestamp":"1717171200","create_date":"2024-05-31 13:00:00"}]`
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// newUpstream serves upstreamPayload and counts the calls; release, when not nil, holds
+// every response until it is closed.
+func newUpstream(t *testing.T, release chan struct{}) (*httptest.Server, *atomic.Int32) {
+	calls := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if release != nil {
+			<-release
+		}
+		w.Write([]byte(upstreamPayload))
+	}))
+	t.Cleanup(server.Close)
+	return server, calls
+}
+
+func newCachedUsecase(upstreamURL string, clock *fakeClock) *Usecase {
+	u := New(context.Background(), repository.NewMemory(), Config{
+		ApiURL:               upstreamURL + "/json/",
+		ApiCallTimeoutMs:     1000,
+		DbOperationTimeoutMs: 100,
+		CacheTTLMs:           1000,
+		CacheStaleMs:         5000,
+	})
+	u.now = clock.Now
+	return u
+}
+
+func TestQuoteCache(t *testing.T) {
+	t.Run("should serve fresh quote from cache", func(t *testing.T) {
+		upstream, calls := newUpstream(t, nil)
+		clock := &fakeClock{now: time.Unix(1717171200, 0)}
+		usecase := newCachedUsecase(upstream.URL, clock)
+
+		first, err := usecase.GetQuote(context.Background(), "USD-BRL")
+		assert.NoError(t, err)
+		assert.False(t, first.Cached)
+		assert.Equal(t, "5.35", first.Quote.Bid.String())
+
+		clock.Advance(400 * time.Millisecond)
+		second, err := usecase.GetQuote(context.Background(), "USD-BRL")
 		assert.NoError(t, err)
+		assert.True(t, second.Cached)
+		assert.Equal(t, 400*time.Millisecond, second.Age)
+		assert.Equal(t, int32(1), calls.Load())
 	})
 
-	t.Run("should get EXTERNAL_API_CALL_TIMEOUT", func(t *testing.T) {
-		ctx := context.Background()
-		db, err := mydb.New(ctx, mydb.Config{File: "sqlite.s3db", RunMigration: true})
+	t.Run("should serve stale quote and revalidate in background", func(t *testing.T) {
+		upstream, calls := newUpstream(t, nil)
+		clock := &fakeClock{now: time.Unix(1717171200, 0)}
+		usecase := newCachedUsecase(upstream.URL, clock)
+
+		_, err := usecase.GetQuote(context.Background(), "USD-BRL")
 		assert.NoError(t, err)
 
-		repo := repository.New(ctx, db.GetConnection())
-		usecase := New(ctx, repo, Config{ApiCallTimeoutMs: 2, DbOperationTimeoutMs: 10})
+		clock.Advance(3 * time.Second)
+		stale, err := usecase.GetQuote(context.Background(), "USD-BRL")
+		assert.NoError(t, err)
+		assert.True(t, stale.Cached)
+		assert.Equal(t, 3*time.Second, stale.Age)
 
-		_, err = usecase.GetDollarQuote()
-		assert.Error(t, err)
-		assert.Equal(t, TimeoutError, err.Error())
+		assert.Eventually(t, func() bool {
+			entry, ok := usecase.cache.get("USD-BRL")
+			return ok && entry.fetchedAt.Equal(clock.Now())
+		}, time.Second, 5*time.Millisecond)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("should fetch again once stale window is over", func(t *testing.T) {
+		upstream, calls := newUpstream(t, nil)
+		clock := &fakeClock{now: time.Unix(1717171200, 0)}
+		usecase := newCachedUsecase(upstream.URL, clock)
 
+		_, err := usecase.GetQuote(context.Background(), "USD-BRL")
+		assert.NoError(t, err)
+
+		clock.Advance(10 * time.Second)
+		result, err := usecase.GetQuote(context.Background(), "USD-BRL")
+		assert.NoError(t, err)
+		assert.False(t, result.Cached)
+		assert.Equal(t, int32(2), calls.Load())
 	})
 
-	t.Run("should get DB_OPERATION_TIMEOUT", func(t *testing.T) {
-		ctx := context.Background()
-		db, err := mydb.New(ctx, mydb.Config{File: "sqlite.s3db", RunMigration: true})
+	t.Run("should coalesce concurrent fetches", func(t *testing.T) {
+		release := make(chan struct{})
+		upstream, calls := newUpstream(t, release)
+		clock := &fakeClock{now: time.Unix(1717171200, 0)}
+		usecase := newCachedUsecase(upstream.URL, clock)
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				result, err := usecase.GetQuote(context.Background(), "USD-BRL")
+				assert.NoError(t, err)
+				assert.Equal(t, "5.35", result.Quote.Bid.String())
+			}()
+		}
+
+		assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, 5*time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("should poll past the cache and refresh it", func(t *testing.T) {
+		upstream, calls := newUpstream(t, nil)
+		clock := &fakeClock{now: time.Unix(1717171200, 0)}
+		usecase := newCachedUsecase(upstream.URL, clock)
+
+		_, err := usecase.GetQuote(context.Background(), "USD-BRL")
 		assert.NoError(t, err)
 
-		repo := repository.New(ctx, db.GetConnection())
-		usecase := New(ctx, repo, Config{ApiCallTimeoutMs: 200, DbOperationTimeoutMs: 0.001})
+		clock.Advance(400 * time.Millisecond)
+		quote, err := usecase.PollQuote(context.Background(), "usd-brl")
+		assert.NoError(t, err)
+		assert.Equal(t, "5.35", quote.Bid.String())
+		assert.Equal(t, int32(2), calls.Load())
+
+		result, err := usecase.GetQuote(context.Background(), "USD-BRL")
+		assert.NoError(t, err)
+		assert.True(t, result.Cached)
+		assert.Equal(t, time.Duration(0), result.Age)
+
+		stored, err := usecase.repo.ListDollarQuotes(context.Background(), q.HistoryFilter{Pair: "USD-BRL", Limit: 10}, time.Second)
+		assert.NoError(t, err)
+		assert.Len(t, stored, 2)
+	})
+}
+
+func TestQuoteFallback(t *testing.T) {
+	slowUpstream := func(t *testing.T) string {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		}))
+		t.Cleanup(server.Close)
+		return server.URL + "/json/"
+	}
+
+	persisted := q.DollarQuote{Code: "USD", Codein: "BRL", Bid: decimal.RequireFromString("5.20"), Ask: decimal.RequireFromString("5.21"), Timestamp: 1717171200}
+
+	newFallbackUsecase := func(t *testing.T, policy string, maxAgeMs int) *Usecase {
+		repo := repository.NewMemory()
+		assert.NoError(t, repo.CreateDollarQuote(context.Background(), persisted, time.Second))
+
+		u := New(context.Background(), repo, Config{
+			ApiURL:               slowUpstream(t),
+			ApiCallTimeoutMs:     20,
+			DbOperationTimeoutMs: 100,
+			DbQueryTimeoutMs:     100,
+			FallbackPolicy:       policy,
+			FallbackMaxAgeMs:     maxAgeMs,
+		})
+		u.now = func() time.Time { return persisted.Time().Add(90 * time.Second) }
+		return u
+	}
+
+	t.Run("should serve last persisted quote on timeout", func(t *testing.T) {
+		usecase := newFallbackUsecase(t, FallbackLastPersisted, 0)
+
+		result, err := usecase.GetQuote(context.Background(), "USD-BRL")
+		assert.NoError(t, err)
+		assert.True(t, result.Stale)
+		assert.Equal(t, "5.2", result.Quote.Bid.String())
+		assert.Equal(t, int64(1717171200), result.Quote.Timestamp)
+		assert.Equal(t, 90*time.Second, result.Age)
+	})
+
+	t.Run("should keep the timeout without fallback policy", func(t *testing.T) {
+		usecase := newFallbackUsecase(t, FallbackNone, 0)
+
+		_, err := usecase.GetQuote(context.Background(), "USD-BRL")
+		assert.ErrorIs(t, err, ErrTimeout)
+	})
+
+	t.Run("should keep the timeout when persisted quote is too old", func(t *testing.T) {
+		usecase := newFallbackUsecase(t, FallbackLastPersisted, 60000)
+
+		_, err := usecase.GetQuote(context.Background(), "USD-BRL")
+		assert.EqualError(t, err, TimeoutError)
+	})
+
+	t.Run("should keep the timeout when nothing was persisted", func(t *testing.T) {
+		usecase := newFallbackUsecase(t, FallbackLastPersisted, 0)
+
+		_, err := usecase.GetQuote(context.Background(), "EUR-BRL")
+		assert.EqualError(t, err, TimeoutError)
+	})
+}
+
+func TestQuoteErrors(t *testing.T) {
+	newUsecase := func(t *testing.T, handler http.HandlerFunc, repo repository.DollarQuoteRepository) *Usecase {
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+		return New(context.Background(), repo, Config{ApiURL: server.URL + "/json/", ApiCallTimeoutMs: 1000, DbOperationTimeoutMs: 100})
+	}
+
+	t.Run("should tell upstream failure apart", func(t *testing.T) {
+		usecase := newUsecase(t, func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}, repository.NewMemory())
+
+		_, err := usecase.GetQuote(context.Background(), "USD-BRL")
+		assert.ErrorIs(t, err, ErrUpstream)
+		assert.NotErrorIs(t, err, ErrTimeout)
+		assert.NotErrorIs(t, err, q.ErrInvalidQuote)
+	})
+
+	t.Run("should tell malformed payload apart", func(t *testing.T) {
+		usecase := newUsecase(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`[{"code":"USD","codein":"BRL","bid":"0","ask":"5.36","timestamp":"1717171200"}]`))
+		}, repository.NewMemory())
+
+		_, err := usecase.GetQuote(context.Background(), "USD-BRL")
+		assert.ErrorIs(t, err, ErrUpstream)
+		assert.ErrorIs(t, err, q.ErrInvalidQuote)
+	})
+
+	t.Run("should pass repository timeout through", func(t *testing.T) {
+		usecase := newUsecase(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(upstreamPayload))
+		}, repository.NewMemory())
+		usecase.cfg.DbOperationTimeoutMs = 0
+
+		_, err := usecase.GetQuote(context.Background(), "USD-BRL")
+		assert.ErrorIs(t, err, repository.ErrTimeout)
+	})
+
+	t.Run("should reject unsupported pair", func(t *testing.T) {
+		_, err := New(context.Background(), repository.NewMemory(), Config{}).GetQuote(context.Background(), "XYZ-BRL")
+		assert.ErrorIs(t, err, q.ErrUnsupportedPair)
+	})
+}
+
+func TestRequestContext(t *testing.T) {
+	// hangingUpstream holds the first call until its request is cancelled and answers
+	// the following ones.
+	hangingUpstream := func(t *testing.T) (string, chan struct{}, chan struct{}) {
+		called, cancelled := make(chan struct{}, 10), make(chan struct{})
+		calls := &atomic.Int32{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called <- struct{}{}
+			if calls.Add(1) == 1 {
+				<-r.Context().Done()
+				close(cancelled)
+				return
+			}
+			w.Write([]byte(upstreamPayload))
+		}))
+		t.Cleanup(server.Close)
+		return server.URL + "/json/", called, cancelled
+	}
+
+	t.Run("should cancel upstream call when caller goes away", func(t *testing.T) {
+		url, called, cancelled := hangingUpstream(t)
+		usecase := New(context.Background(), repository.NewMemory(), Config{ApiURL: url, ApiCallTimeoutMs: 5000, DbOperationTimeoutMs: 100})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-called
+			cancel()
+		}()
+
+		_, err := usecase.GetQuote(ctx, "USD-BRL")
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.NotErrorIs(t, err, ErrTimeout)
+
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("upstream call was not cancelled")
+		}
+	})
+
+	t.Run("should keep serving callers sharing a cancelled fetch", func(t *testing.T) {
+		url, called, _ := hangingUpstream(t)
+		usecase := New(context.Background(), repository.NewMemory(), Config{ApiURL: url, ApiCallTimeoutMs: 5000, DbOperationTimeoutMs: 100})
+
+		leaderCtx, cancel := context.WithCancel(context.Background())
+		leader := make(chan error, 1)
+		go func() {
+			_, err := usecase.GetQuote(leaderCtx, "USD-BRL")
+			leader <- err
+		}()
+		<-called
+
+		follower := make(chan *QuoteResult, 1)
+		go func() {
+			result, err := usecase.GetQuote(context.Background(), "USD-BRL")
+			assert.NoError(t, err)
+			follower <- result
+		}()
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+
+		assert.ErrorIs(t, <-leader, context.Canceled)
+		result := <-follower
+		assert.Equal(t, "5.35", result.Quote.Bid.String())
+	})
+}
+
+func TestReferenceQuote(t *testing.T) {
+	t.Run("should serve a reference rate without storing it", func(t *testing.T) {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"amount":1.0,"base":"USD","date":"2024-05-31","rates":{"BRL":5.2143}}`))
+		}))
+		t.Cleanup(upstream.Close)
+		repo := repository.NewMemory()
+		usecase := NewWithProvider(context.Background(), repo, provider.NewFrankfurter(upstream.URL, nil), Config{ApiCallTimeoutMs: 1000, DbOperationTimeoutMs: 100})
+
+		result, err := usecase.GetQuote(context.Background(), "USD-BRL")
+		assert.NoError(t, err)
+		assert.Equal(t, "5.2143", result.Quote.Bid.String())
+
+		stored, err := repo.ListDollarQuotes(context.Background(), q.HistoryFilter{Limit: 10}, time.Second)
+		assert.NoError(t, err)
+		assert.Empty(t, stored)
+	})
+}
+
+// pairProvider answers every pair with the bid and ask in its prices.
+type pairProvider map[string][2]string
+
+func (pairProvider) Name() string {
+	return "pairs"
+}
+
+func (p pairProvider) FetchQuote(ctx context.Context, pair string) (q.DollarQuote, error) {
+	prices, ok := p[pair]
+	if !ok {
+		return q.DollarQuote{}, q.ErrUnsupportedPair
+	}
+	code, codein, _ := strings.Cut(pair, "-")
+	return q.DollarQuote{Code: code, Codein: codein, Bid: decimal.RequireFromString(prices[0]), Ask: decimal.RequireFromString(prices[1]), Timestamp: 1717171200}, nil
+}
+
+func TestConvert(t *testing.T) {
+	newConverter := func() *Usecase {
+		return NewWithProvider(context.Background(), repository.NewMemory(), pairProvider{
+			"USD-BRL": {"5", "5.5"},
+			"GBP-BRL": {"6.4", "6.5"},
+			"JPY-BRL": {"0.032", "0.033"},
+		}, Config{ApiCallTimeoutMs: 1000, DbOperationTimeoutMs: 100})
+	}
+
+	t.Run("should convert with the direct pair", func(t *testing.T) {
+		conversion, err := newConverter().Convert(context.Background(), "usd", "brl", decimal.RequireFromString("123.45"), "")
+		assert.NoError(t, err)
+		assert.Equal(t, "617.25", conversion.Result.String())
+		assert.Equal(t, "5", conversion.Rate.String())
+		assert.Equal(t, q.SideBid, conversion.Side)
+		assert.Equal(t, []string{"USD-BRL"}, conversion.Pairs)
+		assert.Equal(t, int64(1717171200), conversion.Timestamp)
+	})
+
+	t.Run("should divide by the inverse pair ask", func(t *testing.T) {
+		conversion, err := newConverter().Convert(context.Background(), "BRL", "USD", decimal.NewFromInt(11), q.SideAsk)
+		assert.NoError(t, err)
+		assert.Equal(t, "2", conversion.Result.String())
+	})
+
+	t.Run("should cross through BRL rounding once", func(t *testing.T) {
+		conversion, err := newConverter().Convert(context.Background(), "GBP", "JPY", decimal.NewFromInt(3), q.SideBid)
+		assert.NoError(t, err)
+		assert.Equal(t, "600", conversion.Result.String())
+		assert.Equal(t, "200", conversion.Rate.String())
+		assert.Equal(t, []string{"GBP-BRL", "JPY-BRL"}, conversion.Pairs)
+
+		conversion, err = newConverter().Convert(context.Background(), "BRL", "GBP", decimal.NewFromInt(1), q.SideBid)
+		assert.NoError(t, err)
+		assert.Equal(t, "0.15625", conversion.Result.String())
+	})
+
+	t.Run("should reject bad requests", func(t *testing.T) {
+		_, err := newConverter().Convert(context.Background(), "USD", "BRL", decimal.NewFromInt(1), "mid")
+		assert.ErrorIs(t, err, q.ErrInvalidConversion)
+
+		_, err = newConverter().Convert(context.Background(), "USD", "BRL", decimal.NewFromInt(-1), q.SideBid)
+		assert.ErrorIs(t, err, q.ErrInvalidConversion)
 
-		_, err = usecase.GetDollarQuote()
-		assert.Error(t, err)
-		assert.Equal(t, "DB_OPERATION_TIMEOUT", err.Error())
+		_, err = newConverter().Convert(context.Background(), "USD", "XYZ", decimal.NewFromInt(1), q.SideBid)
+		assert.ErrorIs(t, err, q.ErrUnsupportedConversion)
 	})
 }
@@ -0,0 +1,172 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const InvalidScheduleError = "INVALID_POLL_SCHEDULE"
+
+var ErrInvalidSchedule = errors.New(InvalidScheduleError)
+
+type (
+	// Schedule tells when a job runs next, strictly after the given time.
+	Schedule interface {
+		Next(after time.Time) time.Time
+		String() string
+	}
+
+	every time.Duration
+
+	// cron is a standard five field expression: minute, hour, day of month, month and
+	// day of week. Each field is a bit set of its allowed values.
+	cron struct {
+		spec                          string
+		minute, hour, dom, month, dow uint64
+		domRestricted, dowRestricted  bool
+	}
+
+	field struct {
+		name     string
+		min, max int
+	}
+)
+
+var fields = []field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// maxCronSearch bounds the search of Next for expressions that never match, like
+// the 31st of February.
+const maxCronSearch = 5 * 366 * 24 * time.Hour
+
+// Parse accepts a Go duration (30s, 5m) or a five field cron expression
+// ("*/5 * * * *"), evaluated in the local time zone.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if d, err := time.ParseDuration(spec); err == nil {
+		if d <= 0 {
+			return nil, fmt.Errorf("%w: interval %s must be positive", ErrInvalidSchedule, spec)
+		}
+		return every(d), nil
+	}
+	return parseCron(spec)
+}
+
+func (e every) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(e))
+}
+
+func (e every) String() string {
+	return "every " + time.Duration(e).String()
+}
+
+func parseCron(spec string) (*cron, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("%w: %q is neither a duration nor a five field cron expression", ErrInvalidSchedule, spec)
+	}
+
+	c := &cron{spec: strings.Join(parts, " ")}
+	sets := []*uint64{&c.minute, &c.hour, &c.dom, &c.month, &c.dow}
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrInvalidSchedule, spec, err)
+		}
+		*sets[i] = set
+	}
+	c.domRestricted = parts[2] != "*"
+	c.dowRestricted = parts[4] != "*"
+	if c.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("%w: %q never runs", ErrInvalidSchedule, spec)
+	}
+
+	return c, nil
+}
+
+// parseField reads a comma separated list of *, n, a-b, each optionally followed by
+// a /step.
+func parseField(spec string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(spec, ",") {
+		rng, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			var err error
+			rng = item[:i]
+			if step, err = strconv.Atoi(item[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %s %q", f.name, item)
+			}
+		}
+
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			var err error
+			bounds := strings.SplitN(rng, "-", 2)
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid %s %q", f.name, item)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid %s %q", f.name, item)
+				}
+			} else if step > 1 {
+				hi = f.max
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, fmt.Errorf("%s %q out of range %d-%d", f.name, item, f.min, f.max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// Next walks forward from the minute after after, skipping whole months, days and
+// hours that cannot match.
+func (c *cron) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxCronSearch)
+
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches follows cron: when both day of month and day of week are restricted, a
+// day matching either runs.
+func (c *cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domRestricted && c.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+func (c *cron) String() string {
+	return "cron " + c.spec
+}
@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	at := func(v string) time.Time {
+		parsed, err := time.ParseInLocation("2006-01-02 15:04", v, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	t.Run("should run intervals after the given time", func(t *testing.T) {
+		schedule, err := Parse("30s")
+		assert.NoError(t, err)
+		assert.Equal(t, "every 30s", schedule.String())
+		assert.Equal(t, at("2024-05-31 13:00").Add(30*time.Second), schedule.Next(at("2024-05-31 13:00")))
+	})
+
+	t.Run("should find next cron run", func(t *testing.T) {
+		cases := []struct {
+			spec  string
+			after string
+			want  string
+		}{
+			{"* * * * *", "2024-05-31 13:00", "2024-05-31 13:01"},
+			{"*/15 * * * *", "2024-05-31 13:07", "2024-05-31 13:15"},
+			{"0 9-18 * * 1-5", "2024-05-31 18:30", "2024-06-03 09:00"},
+			{"30 10 1,15 * *", "2024-05-15 10:30", "2024-06-01 10:30"},
+			{"0 0 29 2 *", "2024-03-01 00:00", "2028-02-29 00:00"},
+			{"5/20 0 * * *", "2024-05-31 00:26", "2024-05-31 00:45"},
+			// both days restricted: either the 1st or a Monday
+			{"0 12 1 * 1", "2024-05-28 12:00", "2024-06-01 12:00"},
+		}
+		for _, c := range cases {
+			schedule, err := Parse(c.spec)
+			assert.NoError(t, err, c.spec)
+			assert.Equal(t, at(c.want), schedule.Next(at(c.after)), c.spec)
+		}
+	})
+
+	t.Run("should reject invalid schedules", func(t *testing.T) {
+		for _, spec := range []string{"", "-5s", "0s", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "0 0 31 2 *"} {
+			_, err := Parse(spec)
+			assert.ErrorIs(t, err, ErrInvalidSchedule, spec)
+		}
+	})
+}
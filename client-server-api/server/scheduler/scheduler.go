@@ -0,0 +1,207 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+)
+
+type (
+	// Poller fetches a fresh quote of pair and stores it.
+	Poller interface {
+		PollQuote(ctx context.Context, pair string) (q.DollarQuote, error)
+	}
+
+	Config struct {
+		// Schedules maps each polled pair to its Schedule.
+		Schedules map[string]Schedule
+		// Every run is delayed by a random duration up to Jitter, so pairs sharing a
+		// schedule do not hit the upstream at once.
+		Jitter time.Duration
+		// After a failure the next run waits at least Backoff, doubling on each failure
+		// in a row up to MaxBackoff, even when the schedule is due earlier.
+		Backoff    time.Duration
+		MaxBackoff time.Duration
+	}
+
+	// JobStatus is the state of the polling of one pair; LastError is empty once a run
+	// succeeds again.
+	JobStatus struct {
+		Pair                string     `json:"pair"`
+		Schedule            string     `json:"schedule"`
+		Runs                int        `json:"runs"`
+		Failures            int        `json:"failures"`
+		ConsecutiveFailures int        `json:"consecutiveFailures"`
+		LastRun             *time.Time `json:"lastRun,omitempty"`
+		LastSuccess         *time.Time `json:"lastSuccess,omitempty"`
+		LastError           string     `json:"lastError,omitempty"`
+		LastBid             string     `json:"lastBid,omitempty"`
+		NextRun             *time.Time `json:"nextRun,omitempty"`
+	}
+
+	// Scheduler polls every configured pair in the background once started, one
+	// goroutine per pair, so a slow upstream for one pair does not delay the others.
+	Scheduler struct {
+		cfg       Config
+		poller    Poller
+		ctx       context.Context
+		cancel    context.CancelFunc
+		wg        sync.WaitGroup
+		startOnce sync.Once
+		now       func() time.Time
+
+		mu   sync.Mutex
+		jobs map[string]*JobStatus
+	}
+)
+
+func New(poller Poller, cfg Config) *Scheduler {
+	if cfg.MaxBackoff < cfg.Backoff {
+		cfg.MaxBackoff = cfg.Backoff
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		cfg:    cfg,
+		poller: poller,
+		ctx:    ctx,
+		cancel: cancel,
+		now:    time.Now,
+		jobs:   map[string]*JobStatus{},
+	}
+
+	for pair, schedule := range cfg.Schedules {
+		s.jobs[pair] = &JobStatus{Pair: pair, Schedule: schedule.String()}
+	}
+
+	return s
+}
+
+// Start begins polling every pair; nothing is polled before. Calls after the first one,
+// or after Close, do nothing.
+func (s *Scheduler) Start() {
+	s.startOnce.Do(func() {
+		if s.ctx.Err() != nil {
+			return
+		}
+		for pair, schedule := range s.cfg.Schedules {
+			s.wg.Add(1)
+			go s.run(pair, schedule, s.next(pair, schedule, 0))
+		}
+		if len(s.cfg.Schedules) > 0 {
+			log.Printf("polling %d pairs in the background", len(s.cfg.Schedules))
+		}
+	})
+}
+
+// Status returns the state of every job, ordered by pair.
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := make([]JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		status = append(status, *job)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Pair < status[j].Pair })
+	return status
+}
+
+// Close stops scheduling, cancels the runs in flight and waits for them to return.
+func (s *Scheduler) Close(ctx context.Context) error {
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) run(pair string, schedule Schedule, next time.Time) {
+	defer s.wg.Done()
+
+	for {
+		timer := time.NewTimer(next.Sub(s.now()))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		failures := s.poll(pair)
+		if s.ctx.Err() != nil {
+			return
+		}
+		next = s.next(pair, schedule, failures)
+	}
+}
+
+// poll runs the job once and returns its failures in a row.
+func (s *Scheduler) poll(pair string) int {
+	started := s.now()
+	quote, err := s.poller.PollQuote(s.ctx, pair)
+	if err != nil && s.ctx.Err() != nil {
+		// cancelled by Close, not a failure of the upstream
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := s.jobs[pair]
+	job.Runs++
+	job.LastRun = &started
+	if err != nil {
+		job.Failures++
+		job.ConsecutiveFailures++
+		job.LastError = err.Error()
+		log.Printf("polling %s failed (%d in a row): %v", pair, job.ConsecutiveFailures, err)
+		return job.ConsecutiveFailures
+	}
+
+	job.ConsecutiveFailures = 0
+	job.LastSuccess = &started
+	job.LastError = ""
+	job.LastBid = quote.Bid.String()
+	return 0
+}
+
+// next picks the next run of pair: the schedule plus jitter, but no sooner than the
+// backoff after failures in a row.
+func (s *Scheduler) next(pair string, schedule Schedule, failures int) time.Time {
+	now := s.now()
+	next := schedule.Next(now)
+	if s.cfg.Jitter > 0 {
+		next = next.Add(rand.N(s.cfg.Jitter))
+	}
+	if failures > 0 && s.cfg.Backoff > 0 {
+		backoff := s.cfg.Backoff
+		for i := 1; i < failures && backoff < s.cfg.MaxBackoff; i++ {
+			backoff *= 2
+		}
+		backoff = min(backoff, s.cfg.MaxBackoff)
+		if retry := now.Add(backoff); retry.After(next) {
+			next = retry
+		}
+	}
+
+	s.mu.Lock()
+	s.jobs[pair].NextRun = &next
+	s.mu.Unlock()
+
+	return next
+}
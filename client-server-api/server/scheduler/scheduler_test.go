@@ -0,0 +1,166 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePoller fails the first failures polls of every pair and records when each poll
+// happened.
+type fakePoller struct {
+	mu       sync.Mutex
+	failures int
+	polls    map[string][]time.Time
+	block    bool
+}
+
+func (p *fakePoller) PollQuote(ctx context.Context, pair string) (q.DollarQuote, error) {
+	p.mu.Lock()
+	if p.polls == nil {
+		p.polls = map[string][]time.Time{}
+	}
+	p.polls[pair] = append(p.polls[pair], time.Now())
+	n := len(p.polls[pair])
+	block := p.block
+	p.mu.Unlock()
+
+	if block {
+		<-ctx.Done()
+		return q.DollarQuote{}, ctx.Err()
+	}
+	if n <= p.failures {
+		return q.DollarQuote{}, errors.New("upstream down")
+	}
+	return q.DollarQuote{Code: "USD", Codein: "BRL", Bid: decimal.RequireFromString("5.35")}, nil
+}
+
+func (p *fakePoller) count(pair string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.polls[pair])
+}
+
+func (p *fakePoller) times(pair string) []time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]time.Time(nil), p.polls[pair]...)
+}
+
+func TestScheduler(t *testing.T) {
+	t.Run("should poll every pair on its schedule", func(t *testing.T) {
+		poller := &fakePoller{}
+		s := New(poller, Config{Schedules: map[string]Schedule{
+			"USD-BRL": every(10 * time.Millisecond),
+			"EUR-BRL": every(time.Hour),
+		}})
+		s.Start()
+
+		assert.Eventually(t, func() bool { return poller.count("USD-BRL") >= 3 }, time.Second, 5*time.Millisecond)
+		assert.NoError(t, s.Close(context.Background()))
+		assert.Equal(t, 0, poller.count("EUR-BRL"))
+
+		status := s.Status()
+		assert.Len(t, status, 2)
+		assert.Equal(t, "EUR-BRL", status[0].Pair)
+		assert.Equal(t, 0, status[0].Runs)
+		assert.NotNil(t, status[0].NextRun)
+		assert.Equal(t, "USD-BRL", status[1].Pair)
+		assert.Equal(t, "every 10ms", status[1].Schedule)
+		assert.Equal(t, poller.count("USD-BRL"), status[1].Runs)
+		assert.Equal(t, "5.35", status[1].LastBid)
+		assert.NotNil(t, status[1].LastSuccess)
+		assert.Empty(t, status[1].LastError)
+	})
+
+	t.Run("should back off while the upstream fails", func(t *testing.T) {
+		poller := &fakePoller{failures: 3}
+		s := New(poller, Config{
+			Schedules:  map[string]Schedule{"USD-BRL": every(5 * time.Millisecond)},
+			Backoff:    20 * time.Millisecond,
+			MaxBackoff: 40 * time.Millisecond,
+		})
+		s.Start()
+		defer s.Close(context.Background())
+
+		assert.Eventually(t, func() bool { return poller.count("USD-BRL") >= 5 }, 2*time.Second, 5*time.Millisecond)
+
+		polls := poller.times("USD-BRL")
+		assert.GreaterOrEqual(t, polls[1].Sub(polls[0]), 20*time.Millisecond)
+		assert.GreaterOrEqual(t, polls[2].Sub(polls[1]), 40*time.Millisecond)
+		assert.GreaterOrEqual(t, polls[3].Sub(polls[2]), 40*time.Millisecond, "backoff is capped, not skipped")
+
+		assert.Eventually(t, func() bool {
+			status := s.Status()[0]
+			return status.ConsecutiveFailures == 0 && status.LastError == ""
+		}, time.Second, 5*time.Millisecond)
+		status := s.Status()[0]
+		assert.Equal(t, 3, status.Failures)
+		assert.NotNil(t, status.LastSuccess)
+	})
+
+	t.Run("should report last error", func(t *testing.T) {
+		poller := &fakePoller{failures: 100}
+		s := New(poller, Config{
+			Schedules: map[string]Schedule{"USD-BRL": every(5 * time.Millisecond)},
+			Backoff:   time.Hour,
+		})
+		s.Start()
+		defer s.Close(context.Background())
+
+		assert.Eventually(t, func() bool { return s.Status()[0].Runs == 1 }, time.Second, 5*time.Millisecond)
+		status := s.Status()[0]
+		assert.Equal(t, "upstream down", status.LastError)
+		assert.Equal(t, 1, status.ConsecutiveFailures)
+		assert.Nil(t, status.LastSuccess)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), *status.NextRun, time.Second)
+	})
+
+	t.Run("should delay runs by up to the jitter", func(t *testing.T) {
+		poller := &fakePoller{}
+		before := time.Now()
+		s := New(poller, Config{
+			Schedules: map[string]Schedule{"USD-BRL": every(time.Hour)},
+			Jitter:    time.Minute,
+		})
+		s.Start()
+		defer s.Close(context.Background())
+
+		next := *s.Status()[0].NextRun
+		assert.False(t, next.Before(before.Add(time.Hour)))
+		assert.True(t, next.Before(time.Now().Add(time.Hour+time.Minute)))
+	})
+
+	t.Run("should not poll before start", func(t *testing.T) {
+		poller := &fakePoller{}
+		s := New(poller, Config{Schedules: map[string]Schedule{"USD-BRL": every(time.Millisecond)}})
+
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, 0, poller.count("USD-BRL"))
+		assert.Nil(t, s.Status()[0].NextRun)
+
+		assert.NoError(t, s.Close(context.Background()))
+		s.Start()
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, 0, poller.count("USD-BRL"), "a closed scheduler does not start")
+	})
+
+	t.Run("should cancel polls in flight on close", func(t *testing.T) {
+		poller := &fakePoller{block: true}
+		s := New(poller, Config{Schedules: map[string]Schedule{"USD-BRL": every(time.Millisecond)}})
+		s.Start()
+
+		assert.Eventually(t, func() bool { return poller.count("USD-BRL") == 1 }, time.Second, time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		assert.NoError(t, s.Close(ctx))
+		assert.Equal(t, 0, s.Status()[0].Failures, "a cancelled poll is not a failure")
+	})
+}
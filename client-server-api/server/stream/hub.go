@@ -0,0 +1,196 @@
+package stream
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/repository"
+)
+
+type (
+	HubStats struct {
+		Subscribers int64 `json:"subscribers"`
+		Published   int64 `json:"published"`
+		Delivered   int64 `json:"delivered"`
+		Dropped     int64 `json:"dropped"`
+	}
+
+	// Hub fans the quotes it is given out to its subscribers. Publish never blocks: a
+	// subscriber whose buffer is full is dropped, its channel closed.
+	Hub struct {
+		bufferSize int
+
+		mu          sync.Mutex
+		closed      bool
+		subscribers map[*Subscription]struct{}
+		latest      map[string]q.DollarQuote
+
+		published, delivered, dropped atomic.Int64
+	}
+
+	// Subscription receives the quotes of its pairs on Quotes, which is closed once the
+	// subscriber is dropped, cancelled or the hub closes. Dropped tells the first case.
+	Subscription struct {
+		Quotes  <-chan q.DollarQuote
+		quotes  chan q.DollarQuote
+		pairs   map[string]bool
+		dropped atomic.Bool
+		hub     *Hub
+	}
+
+	// Publisher is a DollarQuoteRepository that publishes every quote it is handed to a
+	// Hub, once the wrapped repository accepted it.
+	Publisher struct {
+		repository.DollarQuoteRepository
+		hub *Hub
+	}
+)
+
+func NewHub(bufferSize int) *Hub {
+	if bufferSize <= 0 {
+		bufferSize = 16
+	}
+
+	return &Hub{
+		bufferSize:  bufferSize,
+		subscribers: map[*Subscription]struct{}{},
+		latest:      map[string]q.DollarQuote{},
+	}
+}
+
+// Subscribe registers a subscriber to pairs, every pair when empty. The newest quote of
+// each of them, when there is one, is already waiting on Quotes. It returns nil after
+// Close.
+func (h *Hub) Subscribe(pairs []string) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil
+	}
+
+	quotes := make(chan q.DollarQuote, max(h.bufferSize, len(h.latest)))
+	s := &Subscription{Quotes: quotes, quotes: quotes, hub: h}
+	if len(pairs) > 0 {
+		s.pairs = make(map[string]bool, len(pairs))
+		for _, pair := range pairs {
+			s.pairs[pair] = true
+		}
+	}
+	for pair, quote := range h.latest {
+		if s.wants(pair) {
+			quotes <- quote
+		}
+	}
+	h.subscribers[s] = struct{}{}
+
+	return s
+}
+
+// Publish hands quote to the subscribers of its pair. A quote equal to the newest one of
+// its pair, as a re-fetch of an upstream that did not move, is skipped.
+func (h *Hub) Publish(quote q.DollarQuote) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	pair := quote.Pair()
+	if latest, ok := h.latest[pair]; ok && latest.Timestamp == quote.Timestamp && latest.Bid.Equal(quote.Bid) && latest.Ask.Equal(quote.Ask) {
+		return
+	}
+	h.latest[pair] = quote
+	h.published.Add(1)
+
+	for s := range h.subscribers {
+		if !s.wants(pair) {
+			continue
+		}
+		select {
+		case s.quotes <- quote:
+			h.delivered.Add(1)
+		default:
+			log.Printf("dropped slow stream subscriber after %d quotes in its buffer", len(s.quotes))
+			s.dropped.Store(true)
+			h.dropped.Add(1)
+			h.remove(s)
+		}
+	}
+}
+
+// Close ends every subscription; later quotes are ignored.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for s := range h.subscribers {
+		h.remove(s)
+	}
+}
+
+func (h *Hub) Stats() HubStats {
+	h.mu.Lock()
+	subscribers := len(h.subscribers)
+	h.mu.Unlock()
+
+	return HubStats{
+		Subscribers: int64(subscribers),
+		Published:   h.published.Load(),
+		Delivered:   h.delivered.Load(),
+		Dropped:     h.dropped.Load(),
+	}
+}
+
+// remove must be called with h.mu held.
+func (h *Hub) remove(s *Subscription) {
+	if _, ok := h.subscribers[s]; ok {
+		delete(h.subscribers, s)
+		close(s.quotes)
+	}
+}
+
+// Cancel unsubscribes s; it is safe to call more than once.
+func (s *Subscription) Cancel() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// Dropped reports whether the subscription ended because its buffer was full.
+func (s *Subscription) Dropped() bool {
+	return s.dropped.Load()
+}
+
+func (s *Subscription) wants(pair string) bool {
+	return s.pairs == nil || s.pairs[pair]
+}
+
+func NewPublisher(repo repository.DollarQuoteRepository, hub *Hub) *Publisher {
+	return &Publisher{DollarQuoteRepository: repo, hub: hub}
+}
+
+func (p *Publisher) CreateDollarQuote(c context.Context, quote q.DollarQuote, t time.Duration) error {
+	if err := p.DollarQuoteRepository.CreateDollarQuote(c, quote, t); err != nil {
+		return err
+	}
+
+	p.hub.Publish(quote)
+	return nil
+}
+
+func (p *Publisher) CreateDollarQuotes(c context.Context, quotes []q.DollarQuote, t time.Duration) error {
+	if err := p.DollarQuoteRepository.CreateDollarQuotes(c, quotes, t); err != nil {
+		return err
+	}
+
+	for _, quote := range quotes {
+		p.hub.Publish(quote)
+	}
+	return nil
+}
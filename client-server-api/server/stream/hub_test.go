@@ -0,0 +1,149 @@
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	q "github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes"
+	"github.com/philippe-berto/pos-goexpert-challenges/client-server-api/server/quotes/repository"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func quote(pair, bid string, timestamp int64) q.DollarQuote {
+	return q.DollarQuote{
+		Code:      pair[:3],
+		Codein:    pair[4:],
+		Bid:       decimal.RequireFromString(bid),
+		Ask:       decimal.RequireFromString(bid),
+		Timestamp: timestamp,
+	}
+}
+
+// drain returns what is buffered on s without waiting.
+func drain(s *Subscription) []q.DollarQuote {
+	var quotes []q.DollarQuote
+	for {
+		select {
+		case quote, ok := <-s.Quotes:
+			if !ok {
+				return quotes
+			}
+			quotes = append(quotes, quote)
+		default:
+			return quotes
+		}
+	}
+}
+
+func TestHub(t *testing.T) {
+	t.Run("should deliver quotes of subscribed pairs", func(t *testing.T) {
+		hub := NewHub(4)
+		usd := hub.Subscribe([]string{"USD-BRL"})
+		all := hub.Subscribe(nil)
+
+		hub.Publish(quote("USD-BRL", "5.35", 1))
+		hub.Publish(quote("EUR-BRL", "5.80", 1))
+
+		assert.Len(t, drain(usd), 1)
+		assert.Len(t, drain(all), 2)
+		assert.Equal(t, HubStats{Subscribers: 2, Published: 2, Delivered: 3}, hub.Stats())
+	})
+
+	t.Run("should start subscriptions with the newest quotes", func(t *testing.T) {
+		hub := NewHub(4)
+		hub.Publish(quote("USD-BRL", "5.35", 1))
+		hub.Publish(quote("USD-BRL", "5.36", 2))
+		hub.Publish(quote("EUR-BRL", "5.80", 1))
+
+		quotes := drain(hub.Subscribe([]string{"USD-BRL"}))
+		assert.Len(t, quotes, 1)
+		assert.Equal(t, "5.36", quotes[0].Bid.String())
+	})
+
+	t.Run("should skip quotes that did not change", func(t *testing.T) {
+		hub := NewHub(4)
+		s := hub.Subscribe(nil)
+
+		hub.Publish(quote("USD-BRL", "5.35", 1))
+		hub.Publish(quote("USD-BRL", "5.35", 1))
+		hub.Publish(quote("USD-BRL", "5.35", 2))
+
+		assert.Len(t, drain(s), 2)
+	})
+
+	t.Run("should drop slow subscriber without blocking", func(t *testing.T) {
+		hub := NewHub(2)
+		slow := hub.Subscribe(nil)
+		fast := hub.Subscribe(nil)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := range int64(5) {
+				hub.Publish(quote("USD-BRL", "5.35", i))
+				drain(fast)
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("publish blocked on a slow subscriber")
+		}
+
+		assert.Len(t, drain(slow), 2, "buffered quotes are still readable")
+		_, open := <-slow.Quotes
+		assert.False(t, open)
+		assert.True(t, slow.Dropped())
+		assert.False(t, fast.Dropped())
+		assert.Equal(t, int64(1), hub.Stats().Dropped)
+		assert.Equal(t, int64(1), hub.Stats().Subscribers)
+	})
+
+	t.Run("should end subscriptions on cancel and close", func(t *testing.T) {
+		hub := NewHub(2)
+		cancelled := hub.Subscribe(nil)
+		open := hub.Subscribe(nil)
+
+		cancelled.Cancel()
+		cancelled.Cancel()
+		_, ok := <-cancelled.Quotes
+		assert.False(t, ok)
+
+		hub.Close()
+		_, ok = <-open.Quotes
+		assert.False(t, ok)
+		assert.False(t, open.Dropped())
+		assert.Nil(t, hub.Subscribe(nil))
+		hub.Publish(quote("USD-BRL", "5.35", 1))
+	})
+}
+
+func TestPublisher(t *testing.T) {
+	t.Run("should publish stored quotes", func(t *testing.T) {
+		hub := NewHub(4)
+		s := hub.Subscribe(nil)
+		publisher := NewPublisher(repository.NewMemory(), hub)
+
+		assert.NoError(t, publisher.CreateDollarQuote(context.Background(), quote("USD-BRL", "5.35", 1), time.Second))
+		assert.NoError(t, publisher.CreateDollarQuotes(context.Background(), []q.DollarQuote{quote("EUR-BRL", "5.80", 1), quote("GBP-BRL", "6.80", 1)}, time.Second))
+
+		assert.Len(t, drain(s), 3)
+		stored, err := publisher.ListDollarQuotes(context.Background(), q.HistoryFilter{Limit: 10}, time.Second)
+		assert.NoError(t, err)
+		assert.Len(t, stored, 3)
+	})
+
+	t.Run("should not publish quotes the repository refused", func(t *testing.T) {
+		hub := NewHub(4)
+		s := hub.Subscribe(nil)
+		publisher := NewPublisher(repository.NewMemory(), hub)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.Error(t, publisher.CreateDollarQuote(ctx, quote("USD-BRL", "5.35", 1), time.Second))
+		assert.Empty(t, drain(s))
+	})
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/multithread/lookup"
+	"github.com/philippe-berto/pos-goexpert-challenges/multithread/models"
+)
+
+const batchTimeout = 5 * time.Second
+
+var csvHeader = []string{"index", "input", "cep", "street", "neighborhood", "city", "state", "ibge", "ddd", "provider", "error"}
+
+type (
+	// batchWriter streams the results of a batch, one row per CEP.
+	batchWriter interface {
+		Write(r lookup.BatchResult) error
+	}
+
+	jsonlWriter struct {
+		enc *json.Encoder
+	}
+
+	csvWriter struct {
+		w      *csv.Writer
+		header bool
+	}
+
+	// batchRow is a JSON line; the address fields are left out when the lookup failed.
+	batchRow struct {
+		Index int    `json:"index"`
+		Input string `json:"input"`
+		*models.Address
+		Provider string `json:"provider,omitempty"`
+		Error    string `json:"error,omitempty"`
+	}
+)
+
+// runBatch looks up the CEPs given as args, or read from -input or stdin, and streams
+// the results to stdout in the order of the input.
+func runBatch(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("batch", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), "usage: multithread batch [flags] [cep...]\n\n"+
+			"Looks up the CEPs given as arguments, or else read from -input or stdin, one per line\n"+
+			"or in the -column of a CSV with a header.\n\n")
+		flags.PrintDefaults()
+	}
+	input := flags.String("input", "", "file to read the CEPs from, - for stdin")
+	column := flags.String("column", "cep", "CSV column holding the CEP; the first one when there is no header")
+	format := flags.String("format", "jsonl", "output format: jsonl or csv")
+	concurrency := flags.Int("concurrency", lookup.DefaultConcurrency, "CEPs looked up at once")
+	viaCepRate := flags.Float64("viacep-rate", 0, "ViaCEP requests per second, 0 for no limit")
+	brasilAPIRate := flags.Float64("brasilapi-rate", 0, "BrasilAPI requests per second, 0 for no limit")
+	timeout := flags.Duration("timeout", batchTimeout, "time limit of each CEP, waiting for a rate limited provider included")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *input != "" && flags.NArg() > 0 {
+		return errors.New("give the CEPs as arguments or -input, not both")
+	}
+	if *concurrency <= 0 {
+		return fmt.Errorf("invalid -concurrency %d", *concurrency)
+	}
+
+	var out batchWriter
+	switch *format {
+	case "jsonl":
+		out = &jsonlWriter{enc: json.NewEncoder(stdout)}
+	case "csv":
+		out = &csvWriter{w: csv.NewWriter(stdout)}
+	default:
+		return fmt.Errorf("unknown -format %q", *format)
+	}
+
+	var viaCep, brasilAPI lookup.CepProvider = lookup.NewViaCep("", nil), lookup.NewBrasilAPI("", nil)
+	if *viaCepRate > 0 {
+		viaCep = lookup.NewRateLimited(viaCep, *viaCepRate)
+	}
+	if *brasilAPIRate > 0 {
+		brasilAPI = lookup.NewRateLimited(brasilAPI, *brasilAPIRate)
+	}
+	batch := lookup.NewBatch(lookup.NewRacer(*timeout, viaCep, brasilAPI), *concurrency)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ceps := make(chan string)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(ceps)
+		if flags.NArg() > 0 {
+			readErr <- sendAll(ctx, flags.Args(), ceps)
+			return
+		}
+		r := stdin
+		if *input != "" && *input != "-" {
+			file, err := os.Open(*input)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			defer file.Close()
+			r = file
+		}
+		readErr <- readCeps(ctx, r, *column, ceps)
+	}()
+
+	total, failed := 0, 0
+	err := batch.Run(ctx, ceps, func(r lookup.BatchResult) error {
+		total++
+		if r.Err != nil {
+			failed++
+		}
+		return out.Write(r)
+	})
+	if err != nil {
+		return err
+	}
+	if err := <-readErr; err != nil {
+		return fmt.Errorf("reading CEPs: %w", err)
+	}
+	log.Printf("looked up %d CEPs, %d failed", total, failed)
+	return nil
+}
+
+func sendAll(ctx context.Context, ceps []string, out chan<- string) error {
+	for _, cep := range ceps {
+		select {
+		case out <- cep:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// readCeps sends the CEPs of r to out. r is a CSV, the CEP in its column field when
+// its first record is a header naming it and in its first field otherwise; a list
+// with one CEP per line is a CSV of one field.
+func readCeps(ctx context.Context, r io.Reader, column string, out chan<- string) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	field, first := 0, true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if first {
+			first = false
+			if i := headerIndex(record, column); i >= 0 {
+				field = i
+				continue
+			}
+		}
+
+		cep := ""
+		if field < len(record) {
+			cep = strings.TrimSpace(record[field])
+		}
+		select {
+		case out <- cep:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func headerIndex(record []string, column string) int {
+	for i, name := range record {
+		if strings.EqualFold(strings.TrimSpace(name), column) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (w *jsonlWriter) Write(r lookup.BatchResult) error {
+	row := batchRow{Index: r.Index, Input: r.Input, Provider: r.Provider}
+	if r.Err != nil {
+		row.Error = r.Err.Error()
+	} else {
+		row.Address = &r.Address
+	}
+	return w.enc.Encode(row)
+}
+
+// Write writes the header before the first row and flushes every row, so the output
+// streams.
+func (w *csvWriter) Write(r lookup.BatchResult) error {
+	if !w.header {
+		w.header = true
+		if err := w.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	errText := ""
+	if r.Err != nil {
+		errText = r.Err.Error()
+	}
+	a := r.Address
+	err := w.w.Write([]string{strconv.Itoa(r.Index), r.Input, a.Cep, a.Street, a.Neighborhood, a.City, a.State, a.Ibge, a.Ddd, r.Provider, errText})
+	if err != nil {
+		return err
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/multithread/lookup"
+	"github.com/philippe-berto/pos-goexpert-challenges/multithread/models"
+)
+
+func TestReadCeps(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"lines", "22461000\n 01153-000\n\n70040010\n", []string{"22461000", "01153-000", "70040010"}},
+		{"csv with header", "name,CEP\nAna,22461000\nBia,01153000\nCaio\n", []string{"22461000", "01153000", ""}},
+		{"csv without header", "22461000,Ana\n01153000,Bia\n", []string{"22461000", "01153000"}},
+	}
+	for _, c := range cases {
+		out := make(chan string, 10)
+		if err := readCeps(context.Background(), strings.NewReader(c.input), "cep", out); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		close(out)
+
+		var got []string
+		for cep := range out {
+			got = append(got, cep)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestBatchWriters(t *testing.T) {
+	results := []lookup.BatchResult{
+		{Index: 1, Input: "22461-000", Address: models.Address{Cep: "22461000", City: "Rio de Janeiro", State: "RJ", Ddd: "21"}, Provider: "Via Cep"},
+		{Index: 2, Input: "123", Err: errors.New(lookup.InvalidCepError)},
+	}
+
+	var jsonlOut, csvOut bytes.Buffer
+	jw, cw := &jsonlWriter{enc: json.NewEncoder(&jsonlOut)}, &csvWriter{w: csv.NewWriter(&csvOut)}
+	for _, r := range results {
+		if err := jw.Write(r); err != nil {
+			t.Fatal(err)
+		}
+		if err := cw.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wantJSONL := `{"index":1,"input":"22461-000","cep":"22461000","street":"","neighborhood":"","city":"Rio de Janeiro","state":"RJ","ddd":"21","provider":"Via Cep"}
+{"index":2,"input":"123","error":"INVALID_CEP"}
+`
+	if jsonlOut.String() != wantJSONL {
+		t.Errorf("jsonl:\n%s\nwant:\n%s", jsonlOut.String(), wantJSONL)
+	}
+
+	wantCSV := `index,input,cep,street,neighborhood,city,state,ibge,ddd,provider,error
+1,22461-000,22461000,,,Rio de Janeiro,RJ,,21,Via Cep,
+2,123,,,,,,,,,INVALID_CEP
+`
+	if csvOut.String() != wantCSV {
+		t.Errorf("csv:\n%s\nwant:\n%s", csvOut.String(), wantCSV)
+	}
+}
@@ -0,0 +1,122 @@
+package lookup
+
+import (
+	"context"
+	"sync"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/multithread/models"
+)
+
+// DefaultConcurrency is the number of CEPs a batch looks up at once by default.
+const DefaultConcurrency = 4
+
+type (
+	// BatchResult is the lookup of the Index-th CEP of a batch, counting from 1. Input
+	// is the CEP as given; Err is set when it could not be looked up, and the other
+	// fields are empty then.
+	BatchResult struct {
+		Index    int
+		Input    string
+		Address  models.Address
+		Provider string
+		Err      error
+	}
+
+	// Batch races the CEPs of a list through a racer, a bounded number at a time. A CEP
+	// only starts while fewer than that many are running or waiting for their turn, so a
+	// slow CEP holds the ones after it back instead of piling their results up.
+	Batch struct {
+		racer       *Racer
+		concurrency int
+	}
+
+	job struct {
+		index int
+		cep   string
+	}
+)
+
+// NewBatch returns a batch running at most concurrency races at once,
+// DefaultConcurrency when not positive.
+func NewBatch(racer *Racer, concurrency int) *Batch {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	return &Batch{racer: racer, concurrency: concurrency}
+}
+
+// Run looks up every CEP received from ceps until it is closed, calling fn with each
+// result in the order the CEPs came in. A CEP that fails does not stop the batch, its
+// result carries the error; Run stops at the first error of fn, returning it, or when
+// ctx is done. Whoever sends to ceps must stop when ctx is done.
+func (b *Batch) Run(ctx context.Context, ceps <-chan string, fn func(BatchResult) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// window holds a slot from the time a CEP starts until its result is handed to fn
+	window := make(chan struct{}, b.concurrency)
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		index := 0
+		for cep := range ceps {
+			index++
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- job{index: index, cep: cep}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := make(chan BatchResult)
+	var wg sync.WaitGroup
+	for range b.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				race, err := b.racer.Race(ctx, j.cep)
+				result := BatchResult{Index: j.index, Input: j.cep, Address: race.Address, Provider: race.Winner, Err: err}
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// results come back in any order; the ones ahead of their turn wait in pending, which
+	// the window keeps under concurrency results
+	pending := make(map[int]BatchResult)
+	next := 1
+	for result := range results {
+		pending[result.Index] = result
+		for {
+			result, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if err := fn(result); err != nil {
+				cancel()
+				for range results {
+				}
+				return err
+			}
+			<-window
+		}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,183 @@
+package lookup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/multithread/models"
+)
+
+// countingProvider answers the CEP it is asked, slower for lower CEPs, and records how
+// many lookups ran at once.
+type countingProvider struct {
+	running, peak atomic.Int32
+}
+
+func (p *countingProvider) Name() string {
+	return "counting"
+}
+
+func (p *countingProvider) Lookup(ctx context.Context, cep string) (models.Address, error) {
+	n := p.running.Add(1)
+	defer p.running.Add(-1)
+	for {
+		peak := p.peak.Load()
+		if n <= peak || p.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+
+	if cep == "00000000" {
+		return models.Address{}, ErrNotFound
+	}
+	time.Sleep(time.Duration('9'-cep[7]) * 5 * time.Millisecond)
+	return models.Address{Cep: cep}, nil
+}
+
+// gatedProvider holds the lookup of the CEP gate until release is closed, and counts the
+// lookups started.
+type gatedProvider struct {
+	gate    string
+	release chan struct{}
+	started atomic.Int32
+}
+
+func (p *gatedProvider) Name() string {
+	return "gated"
+}
+
+func (p *gatedProvider) Lookup(ctx context.Context, cep string) (models.Address, error) {
+	p.started.Add(1)
+	if cep == p.gate {
+		select {
+		case <-p.release:
+		case <-ctx.Done():
+			return models.Address{}, ctx.Err()
+		}
+	}
+	return models.Address{Cep: cep}, nil
+}
+
+func feed(ceps ...string) <-chan string {
+	ch := make(chan string, len(ceps))
+	for _, cep := range ceps {
+		ch <- cep
+	}
+	close(ch)
+	return ch
+}
+
+func TestBatch(t *testing.T) {
+	t.Run("should stream results in input order within the concurrency limit", func(t *testing.T) {
+		provider := &countingProvider{}
+		batch := NewBatch(NewRacer(time.Second, provider), 3)
+
+		var got []BatchResult
+		err := batch.Run(context.Background(), feed("22461001", "22461-002", "00000000", "123", "22461005", "22461009"), func(r BatchResult) error {
+			got = append(got, r)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(got) != 6 {
+			t.Fatalf("got %d results, want 6", len(got))
+		}
+		for i, r := range got {
+			if r.Index != i+1 {
+				t.Errorf("result %d has index %d", i, r.Index)
+			}
+		}
+		if got[1].Input != "22461-002" || got[1].Address.Cep != "22461002" || got[1].Provider != "counting" {
+			t.Errorf("got %+v", got[1])
+		}
+		if !errors.Is(got[2].Err, ErrNotFound) || !errors.Is(got[3].Err, ErrInvalidCep) {
+			t.Errorf("per row errors: %v, %v", got[2].Err, got[3].Err)
+		}
+		if peak := provider.peak.Load(); peak > 3 {
+			t.Errorf("%d lookups ran at once, want at most 3", peak)
+		}
+	})
+
+	t.Run("should not run ahead of a slow CEP by more than the concurrency", func(t *testing.T) {
+		provider := &gatedProvider{gate: "22461001", release: make(chan struct{})}
+		batch := NewBatch(NewRacer(time.Second, provider), 3)
+
+		ceps := make([]string, 50)
+		for i := range ceps {
+			ceps[i] = fmt.Sprintf("224610%02d", i+1)
+		}
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			if started := provider.started.Load(); started > 3 {
+				t.Errorf("%d lookups started behind the slow CEP, want at most 3", started)
+			}
+			close(provider.release)
+		}()
+
+		got := 0
+		err := batch.Run(context.Background(), feed(ceps...), func(r BatchResult) error {
+			got++
+			if r.Index != got || r.Err != nil {
+				t.Errorf("result %d: %+v", got, r)
+			}
+			return nil
+		})
+		if err != nil || got != 50 {
+			t.Errorf("err = %v after %d results, want 50", err, got)
+		}
+	})
+
+	t.Run("should stop at the first error of fn", func(t *testing.T) {
+		batch := NewBatch(NewRacer(time.Second, &countingProvider{}), 2)
+		boom := errors.New("boom")
+
+		calls := 0
+		err := batch.Run(context.Background(), feed("22461001", "22461002", "22461003", "22461004"), func(r BatchResult) error {
+			calls++
+			return boom
+		})
+		if !errors.Is(err, boom) || calls != 1 {
+			t.Errorf("err = %v after %d calls", err, calls)
+		}
+	})
+}
+
+func TestRateLimited(t *testing.T) {
+	t.Run("should space lookups out", func(t *testing.T) {
+		provider := NewRateLimited(fakeProvider{name: "a"}, 50)
+
+		start := time.Now()
+		for range 4 {
+			if _, err := provider.Lookup(context.Background(), "22461000"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// the first lookup is immediate, the next three wait 20ms each
+		if took := time.Since(start); took < 60*time.Millisecond {
+			t.Errorf("4 lookups took %s, want at least 60ms", took)
+		}
+	})
+
+	t.Run("should give a slot back when ctx is done", func(t *testing.T) {
+		provider := NewRateLimited(fakeProvider{name: "a"}, 10)
+		provider.Lookup(context.Background(), "22461000")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := provider.Lookup(ctx, "22461000"); !errors.Is(err, context.Canceled) {
+			t.Fatalf("err = %v", err)
+		}
+
+		start := time.Now()
+		provider.Lookup(context.Background(), "22461000")
+		if took := time.Since(start); took > 150*time.Millisecond {
+			t.Errorf("lookup waited %s for a slot given back", took)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package lookup
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/multithread/models"
+)
+
+// RateLimited spaces the lookups of a provider evenly, so it never gets more than its
+// rate of requests per second whatever the number of goroutines sharing it.
+type RateLimited struct {
+	provider CepProvider
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewRateLimited limits provider to perSecond lookups per second.
+func NewRateLimited(provider CepProvider, perSecond float64) *RateLimited {
+	return &RateLimited{provider: provider, interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+func (p *RateLimited) Name() string {
+	return p.provider.Name()
+}
+
+// Lookup waits for the next free slot of the provider, returning the error of ctx if it
+// is done first, and looks cep up.
+func (p *RateLimited) Lookup(ctx context.Context, cep string) (models.Address, error) {
+	slot := p.reserve()
+	if wait := time.Until(slot); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			p.release(slot)
+			return models.Address{}, ctx.Err()
+		}
+	}
+	return p.provider.Lookup(ctx, cep)
+}
+
+func (p *RateLimited) reserve() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	slot := time.Now()
+	if p.next.After(slot) {
+		slot = p.next
+	}
+	p.next = slot.Add(p.interval)
+	return slot
+}
+
+// release gives an unused slot back when no later one was reserved since.
+func (p *RateLimited) release(slot time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.next.Equal(slot.Add(p.interval)) {
+		p.next = slot
+	}
+}
@@ -0,0 +1,153 @@
+package lookup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/multithread/models"
+)
+
+const (
+	DefaultViaCepURL    = "https://viacep.com.br/ws/"
+	DefaultBrasilAPIURL = "https://brasilapi.com.br/api/cep/v1/"
+
+	InvalidCepError = "INVALID_CEP"
+	NotFoundError   = "CEP_NOT_FOUND"
+	TimeoutError    = "TIMEOUT_ERROR"
+	UpstreamError   = "PROVIDER_FAILED"
+	LostError       = "LOST_RACE"
+)
+
+// Sentinel errors, matched with errors.Is; their message is the error code.
+var (
+	ErrInvalidCep = errors.New(InvalidCepError)
+	ErrNotFound   = errors.New(NotFoundError)
+	ErrTimeout    = errors.New(TimeoutError)
+	ErrUpstream   = errors.New(UpstreamError)
+	// ErrLost is the outcome of a provider cancelled because another one answered first.
+	ErrLost = errors.New(LostError)
+)
+
+type (
+	// CepProvider looks an 8 digit CEP up in one upstream. Providers must stop as soon as
+	// ctx is done, returning its error, and answer ErrNotFound for an unknown CEP.
+	CepProvider interface {
+		Name() string
+		Lookup(ctx context.Context, cep string) (models.Address, error)
+	}
+
+	ViaCepProvider struct {
+		baseURL string
+		client  *http.Client
+	}
+
+	BrasilAPIProvider struct {
+		baseURL string
+		client  *http.Client
+	}
+)
+
+// NewViaCep returns the ViaCEP provider; baseURL defaults to DefaultViaCepURL and
+// client to http.DefaultClient.
+func NewViaCep(baseURL string, client *http.Client) *ViaCepProvider {
+	if baseURL == "" {
+		baseURL = DefaultViaCepURL
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &ViaCepProvider{baseURL: baseURL, client: client}
+}
+
+func (p *ViaCepProvider) Name() string {
+	return "Via Cep"
+}
+
+// Lookup reads the CEP from ViaCEP, which answers an unknown CEP with {"erro": true}.
+func (p *ViaCepProvider) Lookup(ctx context.Context, cep string) (models.Address, error) {
+	var body struct {
+		models.CepVC
+		Erro any `json:"erro"`
+	}
+	if err := get(ctx, p.client, p.baseURL+cep+"/json/", &body); err != nil {
+		return models.Address{}, err
+	}
+	if body.Erro == true || body.Erro == "true" {
+		return models.Address{}, fmt.Errorf("%w: %s", ErrNotFound, cep)
+	}
+	return checked(body.Address())
+}
+
+// NewBrasilAPI returns the BrasilAPI provider; baseURL defaults to DefaultBrasilAPIURL
+// and client to http.DefaultClient.
+func NewBrasilAPI(baseURL string, client *http.Client) *BrasilAPIProvider {
+	if baseURL == "" {
+		baseURL = DefaultBrasilAPIURL
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &BrasilAPIProvider{baseURL: baseURL, client: client}
+}
+
+func (p *BrasilAPIProvider) Name() string {
+	return "Brasil API"
+}
+
+func (p *BrasilAPIProvider) Lookup(ctx context.Context, cep string) (models.Address, error) {
+	var body models.CepBC
+	if err := get(ctx, p.client, p.baseURL+cep, &body); err != nil {
+		return models.Address{}, err
+	}
+	return checked(body.Address())
+}
+
+// checked fails with ErrUpstream when a provider answered an address missing required
+// fields, so the race goes on with the other providers.
+func checked(address models.Address) (models.Address, error) {
+	if err := address.Validate(); err != nil {
+		return models.Address{}, fmt.Errorf("%w: %w", ErrUpstream, err)
+	}
+	return address, nil
+}
+
+// get decodes the JSON answer of url into out. A 404 is ErrNotFound, any other failure
+// of the upstream ErrUpstream; a done ctx is returned as is.
+func get(ctx context.Context, client *http.Client, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %w", ErrUpstream, err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case res.StatusCode != http.StatusOK:
+		return fmt.Errorf("%w: %s", ErrUpstream, res.Status)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %w", ErrUpstream, err)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("%w: %w", ErrUpstream, err)
+	}
+	return nil
+}
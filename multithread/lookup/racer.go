@@ -0,0 +1,133 @@
+package lookup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/multithread/models"
+)
+
+type (
+	// Outcome is how one provider did in a race: Err is nil for the winner, ErrLost for
+	// a provider cancelled once the winner answered, and its own failure otherwise.
+	Outcome struct {
+		Provider string
+		Duration time.Duration
+		Err      error
+	}
+
+	// Result is the address of the winner together with the outcome of every provider,
+	// in the order they were given.
+	Result struct {
+		Address  models.Address
+		Winner   string
+		Outcomes []Outcome
+	}
+
+	// Racer asks every provider at once and keeps the first successful answer.
+	Racer struct {
+		providers []CepProvider
+		timeout   time.Duration
+	}
+
+	outcome struct {
+		index   int
+		address models.Address
+		err     error
+		took    time.Duration
+	}
+)
+
+// NewRacer returns a racer over providers; a race lasts timeout at most, none when zero.
+func NewRacer(timeout time.Duration, providers ...CepProvider) *Racer {
+	return &Racer{providers: providers, timeout: timeout}
+}
+
+// Name lets a racer be a provider of another racer.
+func (r *Racer) Name() string {
+	return "racer"
+}
+
+// Lookup is Race without the outcomes, so the racer is a CepProvider itself.
+func (r *Racer) Lookup(ctx context.Context, cep string) (models.Address, error) {
+	result, err := r.Race(ctx, cep)
+	return result.Address, err
+}
+
+// Race looks cep up with every provider at once. The first successful answer wins and
+// the context of the others is cancelled; Race still waits for them to return, so the
+// Result tells why each one lost. When nobody wins, the error is ErrNotFound if a
+// provider did not know the CEP, ErrTimeout if the race ran out of time, and
+// ErrUpstream joined with every failure otherwise.
+func (r *Racer) Race(ctx context.Context, cep string) (Result, error) {
+	result := Result{Outcomes: make([]Outcome, len(r.providers))}
+	normalized := models.NormalizeCep(cep)
+	if len(normalized) != 8 {
+		return result, fmt.Errorf("%w: %q must have 8 digits", ErrInvalidCep, cep)
+	}
+
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+	raceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := time.Now()
+	outcomes := make(chan outcome, len(r.providers))
+	for i, provider := range r.providers {
+		result.Outcomes[i].Provider = provider.Name()
+		go func() {
+			address, err := provider.Lookup(raceCtx, normalized)
+			outcomes <- outcome{index: i, address: address, err: err, took: time.Since(start)}
+		}()
+	}
+
+	winner := -1
+	for range r.providers {
+		o := <-outcomes
+		err := o.err
+		switch {
+		case err == nil && winner < 0:
+			winner = o.index
+			result.Address = o.address
+			result.Winner = r.providers[o.index].Name()
+			cancel()
+		case err == nil || (winner >= 0 && errors.Is(err, context.Canceled)):
+			err = fmt.Errorf("%w: %s answered first", ErrLost, result.Winner)
+		case errors.Is(err, context.DeadlineExceeded):
+			err = ErrTimeout
+		}
+		result.Outcomes[o.index].Duration = o.took
+		result.Outcomes[o.index].Err = err
+	}
+
+	if winner >= 0 {
+		return result, nil
+	}
+	return result, raceError(ctx, result.Outcomes)
+}
+
+func raceError(ctx context.Context, outcomes []Outcome) error {
+	if len(outcomes) == 0 {
+		return fmt.Errorf("%w: no provider", ErrUpstream)
+	}
+
+	errs := make([]error, 0, len(outcomes))
+	for _, o := range outcomes {
+		if errors.Is(o.Err, ErrNotFound) {
+			return fmt.Errorf("%w: according to %s", ErrNotFound, o.Provider)
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", o.Provider, o.Err))
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("%w: %w", ErrUpstream, errors.Join(errs...))
+}
@@ -0,0 +1,136 @@
+package lookup
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/philippe-berto/pos-goexpert-challenges/multithread/models"
+)
+
+type fakeProvider struct {
+	name    string
+	delay   time.Duration
+	address models.Address
+	err     error
+}
+
+func (p fakeProvider) Name() string {
+	return p.name
+}
+
+func (p fakeProvider) Lookup(ctx context.Context, cep string) (models.Address, error) {
+	select {
+	case <-time.After(p.delay):
+		return p.address, p.err
+	case <-ctx.Done():
+		return models.Address{}, ctx.Err()
+	}
+}
+
+func TestRace(t *testing.T) {
+	address := models.Address{Cep: "22461000", Street: "Rua Lopes Quintas", City: "Rio de Janeiro", State: "RJ"}
+
+	t.Run("should keep the fastest answer and cancel the others", func(t *testing.T) {
+		racer := NewRacer(time.Second,
+			fakeProvider{name: "slow", delay: 500 * time.Millisecond, address: models.Address{Cep: "slow"}},
+			fakeProvider{name: "fast", address: address},
+		)
+
+		start := time.Now()
+		result, err := racer.Race(context.Background(), "22461-000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if took := time.Since(start); took >= 500*time.Millisecond {
+			t.Errorf("race took %s, the slow provider was not cancelled", took)
+		}
+		if result.Winner != "fast" || result.Address != address {
+			t.Errorf("got %+v", result)
+		}
+		if o := result.Outcomes[0]; o.Provider != "slow" || !errors.Is(o.Err, ErrLost) {
+			t.Errorf("slow outcome = %+v", o)
+		}
+		if o := result.Outcomes[1]; o.Provider != "fast" || o.Err != nil {
+			t.Errorf("fast outcome = %+v", o)
+		}
+	})
+
+	t.Run("should skip failing providers", func(t *testing.T) {
+		racer := NewRacer(time.Second,
+			fakeProvider{name: "broken", err: ErrUpstream},
+			fakeProvider{name: "ok", delay: 10 * time.Millisecond, address: address},
+		)
+
+		result, err := racer.Race(context.Background(), "22461000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Winner != "ok" || !errors.Is(result.Outcomes[0].Err, ErrUpstream) {
+			t.Errorf("got %+v", result)
+		}
+	})
+
+	t.Run("should tell why nobody won", func(t *testing.T) {
+		cases := []struct {
+			name      string
+			providers []CepProvider
+			want      error
+		}{
+			{"not found", []CepProvider{fakeProvider{name: "a", err: ErrUpstream}, fakeProvider{name: "b", err: ErrNotFound}}, ErrNotFound},
+			{"timeout", []CepProvider{fakeProvider{name: "a", delay: time.Second}, fakeProvider{name: "b", err: ErrUpstream}}, ErrTimeout},
+			{"failures", []CepProvider{fakeProvider{name: "a", err: ErrUpstream}, fakeProvider{name: "b", err: errors.New("boom")}}, ErrUpstream},
+		}
+		for _, c := range cases {
+			_, err := NewRacer(50*time.Millisecond, c.providers...).Race(context.Background(), "22461000")
+			if !errors.Is(err, c.want) {
+				t.Errorf("%s: err = %v, want %v", c.name, err, c.want)
+			}
+		}
+	})
+
+	t.Run("should reject an invalid cep", func(t *testing.T) {
+		racer := NewRacer(time.Second, fakeProvider{name: "a", address: address})
+		if _, err := racer.Race(context.Background(), "2246100"); !errors.Is(err, ErrInvalidCep) {
+			t.Errorf("err = %v", err)
+		}
+	})
+}
+
+func TestProviders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ws/22461000/json/":
+			w.Write([]byte(`{"cep":"22461-000","logradouro":"Rua Lopes Quintas","bairro":"Jardim Botânico","localidade":"Rio de Janeiro","uf":"RJ"}`))
+		case "/ws/99999999/json/":
+			w.Write([]byte(`{"erro":"true"}`))
+		case "/ws/11111111/json/", "/cep/11111111":
+			w.Write([]byte(`{"cep":"11111111"}`))
+		case "/cep/22461000":
+			w.Write([]byte(`{"cep":"22461000","state":"RJ","city":"Rio de Janeiro","neighborhood":"Jardim Botânico","street":"Rua Lopes Quintas"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	want := models.Address{Cep: "22461000", Street: "Rua Lopes Quintas", Neighborhood: "Jardim Botânico", City: "Rio de Janeiro", State: "RJ"}
+	viaCep := NewViaCep(server.URL+"/ws/", server.Client())
+	brasilAPI := NewBrasilAPI(server.URL+"/cep/", server.Client())
+
+	for _, p := range []CepProvider{viaCep, brasilAPI} {
+		got, err := p.Lookup(context.Background(), "22461000")
+		if err != nil || got != want {
+			t.Errorf("%s: got %+v, %v", p.Name(), got, err)
+		}
+		if _, err := p.Lookup(context.Background(), "99999999"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: err = %v, want ErrNotFound", p.Name(), err)
+		}
+		if _, err := p.Lookup(context.Background(), "11111111"); !errors.Is(err, ErrUpstream) || !errors.Is(err, models.ErrInvalidAddress) {
+			t.Errorf("%s: err = %v, want an invalid address", p.Name(), err)
+		}
+	}
+}
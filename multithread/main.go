@@ -3,138 +3,64 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"io"
+	"errors"
+	"flag"
 	"log"
-	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/philippe-berto/pos-goexpert-challenges/multithread/models"
+	"github.com/philippe-berto/pos-goexpert-challenges/multithread/lookup"
 )
 
 const (
-	cepValue         = "22461000"
-	brasilApiTimeout = 1 * time.Second
-	viaCepTimeout    = 1 * time.Second
-)
-
-type (
-	result struct {
-		cepBC  models.CepBC
-		cepVC  models.CepVC
-		source string
-		err    error
-	}
+	cepValue    = "22461000"
+	raceTimeout = 1 * time.Second
 )
 
 func main() {
-	c := context.Background()
-
-	ch := make(chan result)
-
-	go GetFromViaCep(c, cepValue, ch)
-	go GetFromBrasilCep(c, cepValue, ch)
-
-	select {
-	case result := <-ch:
-		close(ch)
-		if result.err != nil {
-			log.Println(result.err)
+	if len(os.Args) > 1 && os.Args[1] == "batch" {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		err := runBatch(ctx, os.Args[2:], os.Stdin, os.Stdout)
+		stop()
+		switch {
+		case errors.Is(err, flag.ErrHelp):
+			os.Exit(2)
+		case err != nil:
+			log.Println(err)
+			os.Exit(1)
 		}
-
-		if result.source == "Via Cep" {
-			log.Println(result.source)
-			jsonData, err := json.Marshal(result.cepVC)
-			if err != nil {
-				log.Println("Error encoding JSON:", err)
-			} else {
-				log.Println(string(jsonData))
-			}
-		}
-
-		if result.source == "Brasil API" {
-			log.Println(result.source)
-			jsonData, err := json.Marshal(result.cepBC)
-			if err != nil {
-				log.Println("Error encoding JSON:", err)
-			} else {
-				log.Println(string(jsonData))
-			}
-		}
-
-	case <-time.After(1 * time.Second):
-		log.Println("Timeout: no response received within 1 second")
-	}
-}
-
-func GetFromViaCep(c context.Context, cep string, ch chan<- result) {
-	ctx, cancel := context.WithTimeout(c, viaCepTimeout)
-	defer cancel()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://viacep.com.br/ws/"+cep+"/json", nil)
-	if err != nil {
-		log.Println(err)
-	}
-
-	client := &http.Client{}
-	res, err := client.Do(req)
-	switch {
-	case ctx.Err() != nil:
-		log.Println("TIMEOUT_ERROR")
-		return
-	case err != nil:
-		log.Println(err)
-		return
-	}
-
-	defer res.Body.Close()
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		log.Println(err)
 		return
 	}
 
-	cepVC := models.CepVC{}
-	err = json.Unmarshal(body, &cepVC)
-	if err != nil {
-		log.Println(err)
-		return
+	cep := cepValue
+	if len(os.Args) > 1 {
+		cep = os.Args[1]
 	}
 
-	ch <- result{cepVC: cepVC, source: "Via Cep", err: nil}
-}
-
-func GetFromBrasilCep(c context.Context, cep string, ch chan<- result) {
-	ctx, cancel := context.WithTimeout(c, brasilApiTimeout)
-	defer cancel()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://brasilapi.com.br/api/cep/v1/"+cep, nil)
-	if err != nil {
-		log.Println(err)
-		return
+	racer := lookup.NewRacer(raceTimeout, lookup.NewViaCep("", nil), lookup.NewBrasilAPI("", nil))
+	result, err := racer.Race(context.Background(), cep)
+	for _, o := range result.Outcomes {
+		if o.Err != nil {
+			log.Printf("%s lost after %s: %v", o.Provider, o.Duration.Round(time.Millisecond), o.Err)
+		}
 	}
 
-	client := &http.Client{}
-	res, err := client.Do(req)
 	switch {
-	case ctx.Err() != nil:
-		log.Println("TIMEOUT_ERROR")
-		return
+	case errors.Is(err, lookup.ErrTimeout):
+		log.Println("Timeout: no response received within 1 second")
+		os.Exit(1)
 	case err != nil:
 		log.Println(err)
-		return
-	}
-
-	defer res.Body.Close()
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		log.Println(err)
-		return
+		os.Exit(1)
 	}
 
-	cepBC := models.CepBC{}
-	err = json.Unmarshal(body, &cepBC)
+	log.Println(result.Winner)
+	jsonData, err := json.Marshal(result.Address)
 	if err != nil {
-		log.Println(err)
-		return
+		log.Println("Error encoding JSON:", err)
+		os.Exit(1)
 	}
-
-	ch <- result{cepBC: cepBC, source: "Brasil API", err: nil}
+	log.Println(string(jsonData))
 }
@@ -1,5 +1,11 @@
 package models
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
 type (
 	CepVC struct {
 		Cep         string `json:"cep"`
@@ -26,3 +32,74 @@ type (
 		Service      string `json:"service"`
 	}
 )
+
+const (
+	InvalidAddressError = "INVALID_ADDRESS"
+)
+
+// ErrInvalidAddress is returned by Address.Validate.
+var ErrInvalidAddress = errors.New(InvalidAddressError)
+
+// Address is a CEP address, whichever provider it came from. Cep only has its 8 digits;
+// Ibge and Ddd are only known to some providers and are empty otherwise.
+type Address struct {
+	Cep          string `json:"cep"`
+	Street       string `json:"street"`
+	Neighborhood string `json:"neighborhood"`
+	City         string `json:"city"`
+	State        string `json:"state"`
+	Ibge         string `json:"ibge,omitempty"`
+	Ddd          string `json:"ddd,omitempty"`
+}
+
+func (c CepVC) Address() Address {
+	return Address{
+		Cep:          NormalizeCep(c.Cep),
+		Street:       c.Logradouro,
+		Neighborhood: c.Bairro,
+		City:         c.Localidade,
+		State:        c.Uf,
+		Ibge:         c.Ibge,
+		Ddd:          c.Ddd,
+	}
+}
+
+func (c CepBC) Address() Address {
+	return Address{
+		Cep:          NormalizeCep(c.Cep),
+		Street:       c.Street,
+		Neighborhood: c.Neighborhood,
+		City:         c.City,
+		State:        c.State,
+	}
+}
+
+// Validate checks the fields every address has: an 8 digit Cep, a City and a two letter
+// State. Street and Neighborhood are empty for the CEP of a whole town, so they are not
+// required.
+func (a Address) Validate() error {
+	switch {
+	case len(a.Cep) != 8 || NormalizeCep(a.Cep) != a.Cep:
+		return fmt.Errorf("%w: cep %q must have 8 digits", ErrInvalidAddress, a.Cep)
+	case strings.TrimSpace(a.City) == "":
+		return fmt.Errorf("%w: missing city", ErrInvalidAddress)
+	case len(a.State) != 2 || !isUpper(a.State[0]) || !isUpper(a.State[1]):
+		return fmt.Errorf("%w: state %q must be a two letter code", ErrInvalidAddress, a.State)
+	}
+	return nil
+}
+
+// NormalizeCep keeps the digits of cep, so "22461-000" and "22461000" are the same CEP.
+func NormalizeCep(cep string) string {
+	digits := make([]byte, 0, len(cep))
+	for i := 0; i < len(cep); i++ {
+		if cep[i] >= '0' && cep[i] <= '9' {
+			digits = append(digits, cep[i])
+		}
+	}
+	return string(digits)
+}
+
+func isUpper(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAddress(t *testing.T) {
+	want := Address{Cep: "22461000", Street: "Rua Lopes Quintas", Neighborhood: "Jardim Botânico", City: "Rio de Janeiro", State: "RJ"}
+
+	t.Run("should map both providers to the same address", func(t *testing.T) {
+		vc := CepVC{Cep: "22461-000", Logradouro: want.Street, Bairro: want.Neighborhood, Localidade: want.City, Uf: want.State, Ibge: "3304557", Ddd: "21"}
+		bc := CepBC{Cep: "22461000", Street: want.Street, Neighborhood: want.Neighborhood, City: want.City, State: want.State, Service: "open-cep"}
+
+		withCodes := want
+		withCodes.Ibge, withCodes.Ddd = "3304557", "21"
+		if got := vc.Address(); got != withCodes {
+			t.Errorf("CepVC: got %+v, want %+v", got, withCodes)
+		}
+		if got := bc.Address(); got != want {
+			t.Errorf("CepBC: got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("should validate required fields", func(t *testing.T) {
+		town := Address{Cep: "13165000", City: "Engenheiro Coelho", State: "SP"}
+		if err := want.Validate(); err != nil {
+			t.Errorf("%+v: %v", want, err)
+		}
+		if err := town.Validate(); err != nil {
+			t.Errorf("%+v: %v", town, err)
+		}
+
+		invalid := []Address{
+			{Cep: "2246100", City: "Rio de Janeiro", State: "RJ"},
+			{Cep: "22461-00", City: "Rio de Janeiro", State: "RJ"},
+			{Cep: "22461000", City: " ", State: "RJ"},
+			{Cep: "22461000", City: "Rio de Janeiro", State: "rj"},
+			{Cep: "22461000", City: "Rio de Janeiro"},
+		}
+		for _, a := range invalid {
+			if err := a.Validate(); !errors.Is(err, ErrInvalidAddress) {
+				t.Errorf("%+v: err = %v, want ErrInvalidAddress", a, err)
+			}
+		}
+	})
+}